@@ -1,14 +1,36 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"limiu82214/lazyAppleMusic/internal/artwork"
+	"limiu82214/lazyAppleMusic/internal/bridge"
+	"limiu82214/lazyAppleMusic/internal/library"
 	"limiu82214/lazyAppleMusic/internal/tui"
 	"os"
+	"path/filepath"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/godbus/dbus/v5"
 )
 
 func main() {
+	backend := flag.String("backend", "applemusic", "player backend: applemusic, mpris, mpd, fake")
+	mprisPlayer := flag.String("mpris-player", "", "MPRIS player driven by --backend=mpris, like spotify or vlc, empty for the first player found")
+	mpdAddr := flag.String("mpd-addr", "localhost:6600", "address of mpd for --backend=mpd, host:port or the path of a unix socket")
+	mpdPassword := flag.String("mpd-password", "", "password of mpd for --backend=mpd")
+	libraryXml := flag.String("library-xml", "", "Library.xml exported by Music (File > Library > Export Library...), tracks are listed and searched from it")
+	replay := flag.String("replay", "", "recording played back by --backend=applemusic instead of running osascript, see RECORD")
+	fakeLibrary := flag.String("fake-library", "asset/fake_library.json", "library fixture used by --backend=fake")
+	lyricsDirs := flag.String("lyrics-dir", "", "directories of .lrc files, separated by "+string(os.PathListSeparator)+", with mpd the music directory find the files next to the songs")
+	seekStep := flag.Int("seek-step", 5, "seconds to seek with [ and ]")
+	longSeekStep := flag.Int("long-seek-step", 30, "seconds to seek with { and }")
+	artworkKind := flag.String("artwork", string(artwork.KindAuto), "artwork renderer: auto, kitty, sixel, iterm2, truecolor, 256, ascii")
+	artworkCacheSize := flag.Int64("artwork-cache-size", artwork.DefaultMaxBytes>>20, "size cap of the artwork cache in MB")
+	flag.String("process-tag", "", "tag the process, used by `make autoload` to find it")
+	flag.Parse()
+
 	var dump *os.File
 	if _, ok := os.LookupEnv("DEBUG"); ok {
 		var err error
@@ -18,8 +40,91 @@ func main() {
 		}
 	}
 
+	var runner bridge.ScriptRunner
+	if *replay != "" {
+		replayRunner, err := bridge.LoadReplayRunner(*replay)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		runner = replayRunner
+	}
+	// RECORD=path write every script run by the applemusic backend to path, tmp/record.jsonl by default,
+	// with RECORD_REDACT set the names of tracks, albums, artists and playlists are replaced by placeholders
+	if path, ok := os.LookupEnv("RECORD"); ok {
+		if path == "" {
+			path = "tmp/record.jsonl"
+		}
+		record, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer record.Close()
+		if runner == nil {
+			runner = bridge.NewOsascriptRunner()
+		}
+		_, redact := os.LookupEnv("RECORD_REDACT")
+		runner = bridge.NewRecordingRunner(runner, record, redact)
+	}
+
+	artworkRenderer, err := artwork.ParseKind(*artworkKind)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	artworkDir, err := artwork.DefaultDir()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	artworks, err := artwork.NewStore(artworkDir, *artworkCacheSize<<20)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var player bridge.PlayerBridge
+	switch *backend {
+	case "applemusic":
+		player = bridge.NewAppleMusicBridge(dump, runner, artworks)
+	case "mpris":
+		conn, err := dbus.ConnectSessionBus()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer conn.Close()
+		player = bridge.NewMprisBridge(dump, conn, *mprisPlayer, artworks)
+	case "mpd":
+		player = bridge.NewMpdBridge(dump, *mpdAddr, *mpdPassword, artworks)
+	case "fake":
+		lib, err := bridge.LoadFakeLibrary(*fakeLibrary)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		player = bridge.NewFakeBridge(dump, lib, artworks)
+	default:
+		fmt.Printf("unknown backend: %s\n", *backend)
+		os.Exit(1)
+	}
+	if *libraryXml != "" {
+		lib, err := library.Load(*libraryXml)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		player = bridge.NewLibraryBridge(player, lib)
+	}
+
 	//p := tea.NewProgram(internal.InitialModel(dump))
-	p := tea.NewProgram(tui.InitialTopTui(dump))
+	p := tea.NewProgram(tui.InitialTopTui(context.Background(), dump, player, tui.Options{
+		SeekStep:     *seekStep,
+		LongSeekStep: *longSeekStep,
+		LyricsDirs:   filepath.SplitList(*lyricsDirs),
+		Artwork:      artworkRenderer,
+	}))
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Alas, there's been an error: %v", err)
 		os.Exit(1)
@@ -0,0 +1,7 @@
+//go:build !unix
+
+package artwork
+
+func cellSize() (width, height int) {
+	return defaultCellWidth, defaultCellHeight
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package artwork
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// cellSize return the pixel size of a cell, read from the terminal when it tells it.
+func cellSize() (width, height int) {
+	ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Col == 0 || ws.Row == 0 || ws.Xpixel == 0 || ws.Ypixel == 0 {
+		return defaultCellWidth, defaultCellHeight
+	}
+	return int(ws.Xpixel / ws.Col), int(ws.Ypixel / ws.Row)
+}
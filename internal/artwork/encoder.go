@@ -0,0 +1,110 @@
+package artwork
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"strings"
+)
+
+// Encoder turn an image into the text drawing it in cols x rows cells.
+// The text is always rows lines of cols cells, whatever the terminal draw with, so the
+// layout around the artwork doesn't depend on the encoder.
+type Encoder interface {
+	Encode(img image.Image, cols, rows int) (string, error)
+}
+
+// Kind name an encoder, it is the value of the --artwork flag.
+type Kind string
+
+const (
+	KindAuto      Kind = "auto"
+	KindKitty     Kind = "kitty"
+	KindSixel     Kind = "sixel"
+	KindITerm2    Kind = "iterm2"
+	KindTrueColor Kind = "truecolor"
+	KindANSI256   Kind = "256"
+	KindASCII     Kind = "ascii"
+)
+
+var kinds = []Kind{KindAuto, KindKitty, KindSixel, KindITerm2, KindTrueColor, KindANSI256, KindASCII}
+
+func ParseKind(s string) (Kind, error) {
+	for _, kind := range kinds {
+		if Kind(s) == kind {
+			return kind, nil
+		}
+	}
+	names := make([]string, len(kinds))
+	for i, kind := range kinds {
+		names[i] = string(kind)
+	}
+	return "", fmt.Errorf("unknown artwork renderer: %s, expected one of %s", s, strings.Join(names, ", "))
+}
+
+// NewEncoder return the encoder of kind, KindAuto is resolved with Detect.
+func NewEncoder(kind Kind) Encoder {
+	if kind == KindAuto {
+		kind = Detect()
+	}
+	switch kind {
+	case KindKitty:
+		return kittyEncoder{}
+	case KindSixel:
+		return sixelEncoder{}
+	case KindITerm2:
+		return iterm2Encoder{}
+	case KindTrueColor:
+		return blockEncoder{trueColor: true}
+	case KindANSI256:
+		return blockEncoder{}
+	default:
+		return asciiEncoder{}
+	}
+}
+
+// Detect guess the best encoder supported by the terminal from the environment.
+func Detect() Kind {
+	return detect(os.Getenv)
+}
+
+func detect(getenv func(string) string) Kind {
+	term := getenv("TERM")
+	program := getenv("TERM_PROGRAM")
+
+	// tmux doesn't pass graphics through unless told to, stay on text there
+	if getenv("TMUX") == "" {
+		switch {
+		case getenv("KITTY_WINDOW_ID") != "", strings.Contains(term, "kitty"),
+			program == "ghostty", strings.Contains(term, "ghostty"):
+			return KindKitty
+		case program == "iTerm.app", program == "WezTerm":
+			return KindITerm2
+		case strings.HasPrefix(term, "foot"), strings.HasPrefix(term, "mlterm"), program == "contour":
+			return KindSixel
+		}
+	}
+
+	switch colorTerm := getenv("COLORTERM"); {
+	case colorTerm == "truecolor", colorTerm == "24bit":
+		return KindTrueColor
+	case strings.Contains(term, "256color"):
+		return KindANSI256
+	}
+	return KindASCII
+}
+
+// graphicsCells place a graphics escape sequence over cols x rows blank cells.
+// The sequence is drawn from the first cell and the cursor is put back, the blanks
+// take the place of the image for the layout.
+func graphicsCells(sequence string, cols, rows int) string {
+	blank := strings.Repeat(" ", cols)
+	lines := make([]string, rows)
+	for i := range lines {
+		lines[i] = blank
+	}
+	if rows > 0 {
+		lines[0] = "\x1b7" + sequence + "\x1b8" + blank
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,128 @@
+package artwork
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want Kind
+	}{
+		{"kitty", map[string]string{"TERM": "xterm-kitty", "KITTY_WINDOW_ID": "1"}, KindKitty},
+		{"kitty over ssh", map[string]string{"TERM": "xterm-kitty"}, KindKitty},
+		{"ghostty", map[string]string{"TERM_PROGRAM": "ghostty", "TERM": "xterm-ghostty"}, KindKitty},
+		{"iTerm2", map[string]string{"TERM_PROGRAM": "iTerm.app", "TERM": "xterm-256color"}, KindITerm2},
+		{"WezTerm", map[string]string{"TERM_PROGRAM": "WezTerm", "COLORTERM": "truecolor"}, KindITerm2},
+		{"foot", map[string]string{"TERM": "foot-extra"}, KindSixel},
+		{"mlterm", map[string]string{"TERM": "mlterm"}, KindSixel},
+		{"contour", map[string]string{"TERM_PROGRAM": "contour"}, KindSixel},
+		{"kitty in tmux", map[string]string{"TMUX": "/tmp/tmux", "KITTY_WINDOW_ID": "1", "TERM": "tmux-256color"}, KindANSI256},
+		{"iTerm2 in tmux", map[string]string{"TMUX": "/tmp/tmux", "TERM_PROGRAM": "iTerm.app", "COLORTERM": "truecolor"}, KindTrueColor},
+		{"24bit", map[string]string{"TERM": "xterm", "COLORTERM": "24bit"}, KindTrueColor},
+		{"256 colors", map[string]string{"TERM": "xterm-256color"}, KindANSI256},
+		{"dumb", map[string]string{"TERM": "dumb"}, KindASCII},
+		{"nothing", map[string]string{}, KindASCII},
+	}
+	for _, tt := range tests {
+		if got := detect(func(key string) string { return tt.env[key] }); got != tt.want {
+			t.Errorf("%s: detect = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	// Detect read the real environment
+	for _, key := range []string{"TMUX", "KITTY_WINDOW_ID", "TERM_PROGRAM", "COLORTERM"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("TERM", "foot")
+	if got := Detect(); got != KindSixel {
+		t.Errorf("Detect with TERM=foot = %s, want %s", got, KindSixel)
+	}
+	t.Setenv("TMUX", "/tmp/tmux")
+	if got := Detect(); got != KindASCII {
+		t.Errorf("Detect with TERM=foot in tmux = %s, want %s", got, KindASCII)
+	}
+}
+
+func TestParseKind(t *testing.T) {
+	for _, kind := range kinds {
+		if got, err := ParseKind(string(kind)); got != kind || err != nil {
+			t.Errorf("ParseKind(%s) = %s, %v", kind, got, err)
+		}
+	}
+	if _, err := ParseKind("png"); err == nil || !strings.Contains(err.Error(), "kitty, sixel") {
+		t.Errorf("ParseKind(png) = %v, want an error listing the renderers", err)
+	}
+}
+
+// every encoder draw rows lines of cols cells, whatever the image
+func TestEncoderCells(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 8; y++ {
+			img.Set(x, y, color.RGBA{uint8(x * 16), uint8(y * 32), 128, 255})
+		}
+	}
+	for _, kind := range kinds[1:] {
+		for _, size := range [][2]int{{12, 6}, {1, 1}, {0, 0}} {
+			cols, rows := size[0], size[1]
+			text, err := NewEncoder(kind).Encode(img, cols, rows)
+			if err != nil {
+				t.Errorf("%s %dx%d: %v", kind, cols, rows, err)
+				continue
+			}
+			if rows == 0 {
+				continue
+			}
+			lines := strings.Split(text, "\n")
+			if len(lines) != rows {
+				t.Errorf("%s %dx%d: %d lines, want %d", kind, cols, rows, len(lines), rows)
+				continue
+			}
+			for i, line := range lines {
+				// the graphics sequence take no cell, the blanks after it do
+				if at := strings.LastIndex(line, "\x1b8"); at >= 0 {
+					line = line[at+len("\x1b8"):]
+				}
+				if width := lipgloss.Width(line); width != cols {
+					t.Errorf("%s %dx%d: line %d is %d cells, want %d", kind, cols, rows, i, width, cols)
+				}
+			}
+		}
+	}
+}
+
+func TestKittyChunks(t *testing.T) {
+	// a noisy image does not compress, its PNG take several chunks
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	rand.New(rand.NewSource(1)).Read(img.Pix)
+	text, err := kittyEncoder{}.Encode(img, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := strings.Count(text, "\x1b_G")
+	if chunks < 2 {
+		t.Fatalf("%d chunks, want several", chunks)
+	}
+	if strings.Count(text, "m=1;") != chunks-1 || strings.Count(text, "m=0;") != 1 {
+		t.Errorf("every chunk but the last should tell more follow")
+	}
+	if !strings.Contains(text, "a=T,f=100,i=7341,p=1,c=4,r=2,") {
+		t.Errorf("the first chunk does not place the image in 4x2 cells")
+	}
+}
+
+func TestWriteSixelRuns(t *testing.T) {
+	var seq strings.Builder
+	writeSixelRuns(&seq, []byte("??????ab~~~~c"))
+	if got, want := seq.String(), "!6?ab!4~c"; got != want {
+		t.Errorf("writeSixelRuns = %q, want %q", got, want)
+	}
+}
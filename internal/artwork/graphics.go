@@ -0,0 +1,145 @@
+package artwork
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/png"
+	"strings"
+
+	"github.com/anthonynsimon/bild/transform"
+)
+
+// kittyImageId is the id of the artwork in kitty, sending a new artwork replace the previous one.
+const kittyImageId = 7341
+
+// kittyChunkSize is the largest payload of one escape sequence allowed by the kitty protocol.
+const kittyChunkSize = 4096
+
+// kittyEncoder send the image with the kitty graphics protocol, kitty scale it to the cells.
+// https://sw.kovidgoyal.net/kitty/graphics-protocol/
+type kittyEncoder struct{}
+
+func (kittyEncoder) Encode(img image.Image, cols, rows int) (string, error) {
+	data, err := encodePNG(img)
+	if err != nil {
+		return "", err
+	}
+
+	var seq strings.Builder
+	for len(data) > 0 {
+		chunk := data[:min(kittyChunkSize, len(data))]
+		data = data[len(chunk):]
+		more := 0
+		if len(data) > 0 {
+			more = 1
+		}
+		if seq.Len() == 0 {
+			// C=1 keep the cursor in place, q=2 silence the responses
+			fmt.Fprintf(&seq, "\x1b_Ga=T,f=100,i=%d,p=1,c=%d,r=%d,C=1,q=2,m=%d;%s\x1b\\", kittyImageId, cols, rows, more, chunk)
+		} else {
+			fmt.Fprintf(&seq, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	return graphicsCells(seq.String(), cols, rows), nil
+}
+
+// iterm2Encoder send the image as an iTerm2 inline image, iTerm2 scale it to the cells.
+// https://iterm2.com/documentation-images.html
+type iterm2Encoder struct{}
+
+func (iterm2Encoder) Encode(img image.Image, cols, rows int) (string, error) {
+	data, err := encodePNG(img)
+	if err != nil {
+		return "", err
+	}
+	seq := fmt.Sprintf("\x1b]1337;File=inline=1;width=%d;height=%d;preserveAspectRatio=0:%s\a", cols, rows, data)
+	return graphicsCells(seq, cols, rows), nil
+}
+
+func encodePNG(img image.Image) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", fmt.Errorf("error encoding artwork: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// the pixel size of a cell when the terminal doesn't tell it
+const (
+	defaultCellWidth  = 10
+	defaultCellHeight = 20
+)
+
+// sixelEncoder draw the image with sixels, the terminal draw pixels as they are so the
+// image is resized to the pixel size of the cells.
+type sixelEncoder struct{}
+
+func (sixelEncoder) Encode(img image.Image, cols, rows int) (string, error) {
+	cellWidth, cellHeight := cellSize()
+	width, height := cols*cellWidth, rows*cellHeight
+	if width <= 0 || height <= 0 {
+		return graphicsCells("", cols, rows), nil
+	}
+
+	scaled := transform.Resize(img, width, height, transform.Linear)
+	paletted := image.NewPaletted(scaled.Bounds(), palette.WebSafe)
+	draw.FloydSteinberg.Draw(paletted, paletted.Bounds(), scaled, image.Point{})
+
+	var seq strings.Builder
+	// 7 (aspect ratio 1:1), 1 (pixels without color stay as they are)
+	fmt.Fprintf(&seq, "\x1bP7;1;q\"1;1;%d;%d", width, height)
+	for i, c := range paletted.Palette {
+		r, g, b, _ := c.RGBA()
+		fmt.Fprintf(&seq, "#%d;2;%d;%d;%d", i, r*100/0xffff, g*100/0xffff, b*100/0xffff)
+	}
+
+	band := make([]byte, width)
+	for y := 0; y < height; y += 6 {
+		used := make([]bool, len(paletted.Palette))
+		for dy := 0; dy < 6 && y+dy < height; dy++ {
+			for x := 0; x < width; x++ {
+				used[paletted.ColorIndexAt(x, y+dy)] = true
+			}
+		}
+		for colorIdx, ok := range used {
+			if !ok {
+				continue
+			}
+			for x := 0; x < width; x++ {
+				bits := byte(0)
+				for dy := 0; dy < 6 && y+dy < height; dy++ {
+					if int(paletted.ColorIndexAt(x, y+dy)) == colorIdx {
+						bits |= 1 << dy
+					}
+				}
+				band[x] = '?' + bits
+			}
+			fmt.Fprintf(&seq, "#%d", colorIdx)
+			writeSixelRuns(&seq, band)
+			seq.WriteByte('$') // back to the start of the band
+		}
+		seq.WriteByte('-') // next band
+	}
+	seq.WriteString("\x1b\\")
+	return graphicsCells(seq.String(), cols, rows), nil
+}
+
+// writeSixelRuns write band with runs of the same sixel compressed as !<count><sixel>.
+func writeSixelRuns(seq *strings.Builder, band []byte) {
+	for i := 0; i < len(band); {
+		j := i
+		for j < len(band) && band[j] == band[i] {
+			j++
+		}
+		if count := j - i; count > 3 {
+			fmt.Fprintf(seq, "!%d%c", count, band[i])
+		} else {
+			seq.WriteString(strings.Repeat(string(band[i]), count))
+		}
+		i = j
+	}
+}
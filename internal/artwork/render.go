@@ -0,0 +1,78 @@
+package artwork
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"sync"
+)
+
+// DefaultRenderCacheSize is the number of rendered artworks kept in memory.
+const DefaultRenderCacheSize = 32
+
+type renderKey struct {
+	path       string
+	cols, rows int
+}
+
+// Renderer turn artwork files into terminal strings with its encoder.
+// The rendered strings are kept in memory, so a resize back to a known size cost nothing.
+type Renderer struct {
+	encoder Encoder
+
+	mu      sync.Mutex
+	size    int
+	entries map[renderKey]string
+	order   []renderKey // oldest first
+}
+
+func NewRenderer(size int, encoder Encoder) *Renderer {
+	if size <= 0 {
+		size = DefaultRenderCacheSize
+	}
+	return &Renderer{
+		encoder: encoder,
+		size:    size,
+		entries: map[renderKey]string{},
+	}
+}
+
+// Render return the artwork at path drawn in cols x rows cells.
+func (r *Renderer) Render(path string, cols, rows int) (string, error) {
+	key := renderKey{path, cols, rows}
+	r.mu.Lock()
+	text, ok := r.entries[key]
+	r.mu.Unlock()
+	if ok {
+		return text, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("error opening artwork: %v", err)
+	}
+	img, _, err := image.Decode(file)
+	file.Close()
+	if err != nil {
+		return "", fmt.Errorf("error decoding artwork: %v", err)
+	}
+	text, err = r.encoder.Encode(img, cols, rows)
+	if err != nil {
+		return "", fmt.Errorf("error encoding artwork: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.entries[key]; !ok {
+		r.entries[key] = text
+		r.order = append(r.order, key)
+		for len(r.order) > r.size {
+			delete(r.entries, r.order[0])
+			r.order = r.order[1:]
+		}
+	}
+	return text, nil
+}
@@ -0,0 +1,147 @@
+package artwork
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxBytes is the size cap of the artwork directory.
+const DefaultMaxBytes = 64 << 20
+
+const tempSuffix = ".tmp"
+
+// Store keep artwork files in a cache directory, the least recently used files are
+// removed once the directory grows over maxBytes.
+// Files are written to a temporary name then renamed, so instances sharing the
+// directory never read a half written file.
+type Store struct {
+	dir      string
+	maxBytes int64
+
+	mu sync.Mutex
+}
+
+// DefaultDir return the artwork directory inside the user cache directory ($XDG_CACHE_HOME on linux).
+func DefaultDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting user cache dir: %v", err)
+	}
+	return filepath.Join(base, "lazyAppleMusic", "artwork"), nil
+}
+
+func NewStore(dir string, maxBytes int64) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating artwork dir: %v", err)
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	return &Store{dir: dir, maxBytes: maxBytes}, nil
+}
+
+// Key return the cache key of the artwork of track.
+// Tracks of the same album share their artwork, a track without album fall back to its persistent ID.
+func Key(track model.Track) string {
+	if track.Album == "" {
+		if track.Id == "" {
+			return ""
+		}
+		return "track-" + track.Id
+	}
+	artist := track.AlbumArtist
+	if artist == "" {
+		artist = track.Artist
+	}
+	sum := sha1.Sum([]byte(artist + "\x00" + track.Album))
+	return "album-" + hex.EncodeToString(sum[:10])
+}
+
+func (s *Store) path(key string) string {
+	return filepath.Join(s.dir, key)
+}
+
+// Lookup return the path of the cached artwork of key.
+func (s *Store) Lookup(key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+	path := s.path(key)
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	// the modification time is the last use of the file
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return path, true
+}
+
+// Put call write with a temporary path, then move the written file to the path of key.
+func (s *Store) Put(key string, write func(path string) error) (string, error) {
+	if key == "" {
+		return "", fmt.Errorf("error storing artwork: empty key")
+	}
+	tmp, err := os.CreateTemp(s.dir, key+"-*"+tempSuffix)
+	if err != nil {
+		return "", fmt.Errorf("error storing artwork: %v", err)
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	if err := write(tmpPath); err != nil {
+		return "", err
+	}
+	path := s.path(key)
+	if err := os.Rename(tmpPath, path); err != nil {
+		return "", fmt.Errorf("error storing artwork: %v", err)
+	}
+	s.prune()
+	return path, nil
+}
+
+// prune remove the least recently used files until the directory fit in maxBytes.
+func (s *Store) prune() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	type file struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	files := []file{}
+	total := int64(0)
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), tempSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, file{s.path(entry.Name()), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for _, f := range files {
+		if total <= s.maxBytes {
+			break
+		}
+		if err := os.Remove(f.path); err == nil {
+			total -= f.size
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package artwork
+
+import (
+	"errors"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestKey(t *testing.T) {
+	album := Key(model.Track{Id: "T1", Artist: "Band", Album: "Album"})
+	tests := []struct {
+		name  string
+		track model.Track
+		same  bool // same key as the album
+	}{
+		{"another track of the album", model.Track{Id: "T2", Artist: "Band", Album: "Album"}, true},
+		{"the album artist win over the artist", model.Track{Id: "T3", Artist: "Guest", AlbumArtist: "Band", Album: "Album"}, true},
+		{"another album", model.Track{Id: "T1", Artist: "Band", Album: "Other"}, false},
+		{"the album of another artist", model.Track{Id: "T1", Artist: "Other Band", Album: "Album"}, false},
+		{"the names are not glued", model.Track{Id: "T1", Artist: "BandA", Album: "lbum"}, false},
+	}
+	for _, tt := range tests {
+		if got := Key(tt.track); (got == album) != tt.same {
+			t.Errorf("%s: Key = %s, album key %s, want same %v", tt.name, got, album, tt.same)
+		}
+	}
+
+	if got := Key(model.Track{Id: "T1"}); got != "track-T1" {
+		t.Errorf("Key without album = %s, want track-T1", got)
+	}
+	if got := Key(model.Track{}); got != "" {
+		t.Errorf("Key of no track = %s, want empty", got)
+	}
+}
+
+// putFile store size bytes under key, then date its last use
+func putFile(t *testing.T, s *Store, key string, size int, lastUse time.Time) {
+	t.Helper()
+	path, err := s.Put(key, func(path string) error {
+		return os.WriteFile(path, make([]byte, size), 0o644)
+	})
+	if err != nil {
+		t.Fatalf("Put(%s): %v", key, err)
+	}
+	if err := os.Chtimes(path, lastUse, lastUse); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func storedKeys(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{}
+	for _, entry := range entries {
+		keys = append(keys, entry.Name())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestStorePrune(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewStore(dir, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	putFile(t, s, "a", 10, now.Add(-3*time.Hour))
+	putFile(t, s, "b", 10, now.Add(-2*time.Hour))
+	putFile(t, s, "c", 10, now.Add(-1*time.Hour))
+	if got, want := storedKeys(t, dir), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("stored = %v, want %v, the directory fit", got, want)
+	}
+
+	// a lookup is a use, b is now the least recently used
+	if path, ok := s.Lookup("a"); !ok || path != filepath.Join(dir, "a") {
+		t.Fatalf("Lookup(a) = %s, %v", path, ok)
+	}
+	putFile(t, s, "d", 10, now)
+	if got, want := storedKeys(t, dir), []string{"a", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stored = %v, want %v", got, want)
+	}
+	if _, ok := s.Lookup("b"); ok {
+		t.Error("Lookup(b) found the removed file")
+	}
+
+	// the files are removed oldest first until the directory fit
+	putFile(t, s, "e", 25, now.Add(time.Hour))
+	if got, want := storedKeys(t, dir), []string{"e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stored = %v, want %v", got, want)
+	}
+}
+
+// a failed write, like a track without artwork, store nothing, not even an empty file
+func TestStorePutFailed(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errNoArtwork := errors.New("no artwork")
+	if _, err := s.Put("a", func(path string) error { return errNoArtwork }); !errors.Is(err, errNoArtwork) {
+		t.Errorf("Put = %v, want the error of write", err)
+	}
+	if _, ok := s.Lookup("a"); ok {
+		t.Error("Lookup found the artwork whose write failed")
+	}
+	if got := storedKeys(t, dir); len(got) != 0 {
+		t.Errorf("stored = %v, want nothing", got)
+	}
+	if _, err := s.Put("", func(path string) error { return nil }); err == nil {
+		t.Error("Put with an empty key succeed")
+	}
+}
@@ -0,0 +1,47 @@
+package artwork
+
+import (
+	"image"
+	"strings"
+
+	"github.com/BigJk/imeji"
+)
+
+// blockEncoder draw the image with colored block characters.
+type blockEncoder struct {
+	trueColor bool
+}
+
+func (e blockEncoder) Encode(img image.Image, cols, rows int) (string, error) {
+	colorOpt := imeji.WithANSI256()
+	if e.trueColor {
+		colorOpt = imeji.WithTrueColor() // 24-bit
+	}
+	text, err := imeji.ImageString(img, imeji.WithResize(cols, rows), colorOpt)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(text, "\n"), nil
+}
+
+// asciiEncoder draw a frame in place of the image, for terminals without colors.
+type asciiEncoder struct{}
+
+func (asciiEncoder) Encode(img image.Image, cols, rows int) (string, error) {
+	if cols < 2 || rows < 2 {
+		return strings.TrimSuffix(strings.Repeat(strings.Repeat(" ", cols)+"\n", rows), "\n"), nil
+	}
+	const label = "artwork"
+	lines := make([]string, rows)
+	border := "+" + strings.Repeat("-", cols-2) + "+"
+	blank := "|" + strings.Repeat(" ", cols-2) + "|"
+	for i := range lines {
+		lines[i] = blank
+	}
+	lines[0], lines[rows-1] = border, border
+	if rows > 2 && cols-2 >= len(label) {
+		pad := (cols - 2 - len(label)) / 2
+		lines[rows/2] = "|" + strings.Repeat(" ", pad) + label + strings.Repeat(" ", cols-2-pad-len(label)) + "|"
+	}
+	return strings.Join(lines, "\n"), nil
+}
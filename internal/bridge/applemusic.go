@@ -1,52 +1,103 @@
 package bridge
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"limiu82214/lazyAppleMusic/internal/artwork"
 	"limiu82214/lazyAppleMusic/internal/constant"
 	"limiu82214/lazyAppleMusic/internal/model"
 
-	"os/exec"
-	"regexp"
 	"strconv"
 	"strings"
-
-	"github.com/BigJk/imeji"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/davecgh/go-spew/spew"
 )
 
 type PlayerBridge interface {
-	PlayPause() tea.Cmd
-	Play() tea.Cmd
-	Pause() tea.Cmd
-	NextTrack() tea.Cmd
-	PreviousTrack() tea.Cmd
-	SetVolume(volume int) tea.Cmd
-	IncreaseVolume() tea.Cmd
-	DecreaseVolume() tea.Cmd
-	PlayPlaylist(playlistName string) tea.Cmd
-	PlayTrackById(id string) tea.Cmd
-	FavoriteCurrentTrack() tea.Cmd
-	FavoriteTrackByTrackId(id string) tea.Cmd
-
-	GetPlayerPosition() (int, error)
-	GetCurrentAlbum(width, height int) (string, error)
-	GetCurrentTrack() (model.Track, error)
-	GetPlaylists() ([]string, error)
-	GetCurrentPlaylist() (model.Playlist, error)
+	LaunchApp(ctx context.Context) tea.Cmd
+	PlayPause(ctx context.Context) tea.Cmd
+	Play(ctx context.Context) tea.Cmd
+	Pause(ctx context.Context) tea.Cmd
+	NextTrack(ctx context.Context) tea.Cmd
+	PreviousTrack(ctx context.Context) tea.Cmd
+	SetVolume(ctx context.Context, volume int) tea.Cmd
+	IncreaseVolume(ctx context.Context) tea.Cmd
+	DecreaseVolume(ctx context.Context) tea.Cmd
+	PlayPlaylist(ctx context.Context, playlistName string) tea.Cmd
+	PlayTrackById(ctx context.Context, id string) tea.Cmd
+	PlayTrackInPlaylist(ctx context.Context, playlistId, trackId string) tea.Cmd
+	FavoriteCurrentTrack(ctx context.Context) tea.Cmd
+	FavoriteTrackByTrackId(ctx context.Context, id string) tea.Cmd
+	SetShuffle(ctx context.Context, enabled bool) tea.Cmd
+	SetRepeat(ctx context.Context, mode model.RepeatMode) tea.Cmd
+	SetPlayerPosition(ctx context.Context, seconds int) tea.Cmd
+	SetRating(ctx context.Context, trackId string, rating int) tea.Cmd
+	SetDisliked(ctx context.Context, trackId string, disliked bool) tea.Cmd
+	CreatePlaylist(ctx context.Context, name string) tea.Cmd
+	RenamePlaylist(ctx context.Context, playlistId, name string) tea.Cmd
+	DeletePlaylist(ctx context.Context, playlistId string) tea.Cmd
+	AddTracksToPlaylist(ctx context.Context, playlistId string, trackIds []string) tea.Cmd
+	RemoveTracksFromPlaylist(ctx context.Context, playlistId string, trackIds []string) tea.Cmd
+	ReorderPlaylist(ctx context.Context, playlistId string, trackIds []string) tea.Cmd
+	Seek(ctx context.Context, deltaSeconds int) tea.Cmd
+
+	GetNowPlaying(ctx context.Context) (model.NowPlaying, error)
+	GetPlayerState(ctx context.Context) (model.PlayerState, error)
+	GetShuffle(ctx context.Context) (bool, error)
+	GetRepeat(ctx context.Context) (model.RepeatMode, error)
+	GetPlayerPosition(ctx context.Context) (int, error)
+	GetArtwork(ctx context.Context, track model.Track) (string, error)
+	GetCurrentTrack(ctx context.Context) (model.Track, error)
+	GetPlaylists(ctx context.Context) ([]model.Playlist, error)
+	GetUserPlaylists(ctx context.Context) ([]model.Playlist, error)
+	GetCurrentPlaylistInfo(ctx context.Context) (model.Playlist, error)
+	GetCurrentPlaylistTracks(ctx context.Context, offset, limit int) ([]model.Track, error)
+	GetTrackById(ctx context.Context, id string) (model.Track, error)
+	SearchLibrary(ctx context.Context, query string, field model.SearchField) ([]model.Track, error)
+}
+
+// Watcher is implemented by the bridges the player can push its changes to, instead of being polled.
+type Watcher interface {
+	// WatchChanges wait for the next change of the player and report it as constant.EventPlayerChanged,
+	// it report nil once ctx is done.
+	WatchChanges(ctx context.Context) tea.Cmd
 }
+
+// searchLimit cap the results of SearchLibrary, every result cost a few Apple events
+const searchLimit = 200
+
 type appleMusicBridge struct {
 	appName string
 	dump    io.Writer
+	runner  ScriptRunner
+	scripts scriptBuilder
+
+	artworks *artwork.Store
 }
 
-func NewAppleMusicBridge(dump io.Writer) PlayerBridge {
+// errNoArtwork abort storing the artwork of a track without artwork
+var errNoArtwork = errors.New("no artwork")
+
+// NewAppleMusicBridge create a bridge talking to Music.app through runner.
+// A nil runner means the default osascript runner, artworks are exported into artworks.
+func NewAppleMusicBridge(dump io.Writer, runner ScriptRunner, artworks *artwork.Store) PlayerBridge {
+	if runner == nil {
+		runner = NewOsascriptRunner()
+	}
 
+	appName := "Music"
 	return &appleMusicBridge{
-		appName: "Music",
+		appName: appName,
 		dump:    dump,
+		runner:  runner,
+		scripts: scriptBuilder{appName: appName},
+
+		artworks: artworks,
 	}
 }
 
@@ -57,97 +108,193 @@ func (a *appleMusicBridge) log(msg interface{}) {
 	}
 }
 
-func (a *appleMusicBridge) parseAppleRecord(input string) map[string]string {
-	re := regexp.MustCompile(`(\w[\w ]*?):\s*([^,]*?)\s*(?:,|$)`)
-	matches := re.FindAllStringSubmatch(input, -1)
+// Default timeouts of the scripts, so a hung osascript (Music showing a modal dialog) fail with ErrTimeout
+// instead of blocking its command forever. The context of the caller can only make them shorter.
+const (
+	controlTimeout = 5 * time.Second  // playback, volume, favorites, ratings
+	queryTimeout   = 4 * time.Second  // the getters polled every tick, done before the next tick
+	launchTimeout  = 30 * time.Second // Music can be slow to start
+	libraryTimeout = 30 * time.Second // search, playlists and artworks go through the whole library
+)
 
-	result := make(map[string]string)
-	for _, m := range matches {
-		key := strings.TrimSpace(m[1])
-		val := strings.TrimSpace(m[2])
-		result[key] = val
-	}
-	return result
+// run execute script within timeout and return its stdout
+func (a *appleMusicBridge) run(ctx context.Context, timeout time.Duration, script Script) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, err := a.runner.Run(ctx, script)
+	return result.Stdout, err
 }
-func (a *appleMusicBridge) appleTrackRecordMap2Track(m map[string]string) model.Track {
-	duration, _ := strconv.ParseFloat(m["duration"], 64)
-	playedCount, _ := strconv.Atoi(m["played count"])
 
-	track := model.Track{
-		Id:          m["persistent ID"],
-		Name:        m["name"],
-		Time:        m["time"],
-		Duration:    duration,
-		PlayedCount: playedCount,
-		Favorited:   m["favorited"] == "true",
-		Album:       m["album"],
-		AlbumArtist: m["album artist"],
-		Artist:      m["artist"],
-		Lyrics:      m["lyrics"],
+// runJSON execute script within timeout and decode the JSON it print into v
+func (a *appleMusicBridge) runJSON(ctx context.Context, timeout time.Duration, script Script, v any) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, err := a.runner.Run(ctx, script)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(result.Stdout), v); err != nil {
+		return &ParseError{Output: result.Stdout, Err: err}
 	}
-	return track
+	return nil
 }
 
-func (a *appleMusicBridge) GetCurrentTrack() (model.Track, error) {
+// jxaPrelude is prepended to every JXA script by scriptBuilder.
+const jxaPrelude = `
+function trackJSON(p) {
+	return {
+		id: p.persistentID,
+		name: p.name,
+		time: p.time,
+		duration: p.duration,
+		playedCount: p.playedCount,
+		favorited: p.favorited,
+		rating: p.rating,
+		disliked: p.disliked,
+		artist: p.artist,
+		album: p.album,
+		albumArtist: p.albumArtist,
+		lyrics: p.lyrics,
+	};
+}
+`
+
+func (a *appleMusicBridge) GetCurrentTrack(ctx context.Context) (model.Track, error) {
 	nullTrack := model.Track{Name: "No Track Playing"}
-	script := fmt.Sprintf(`
-		tell application "%s"
-			set output to {}
-			set end of output to properties of current track
-		end tell
-		return output
-	`, a.appName)
-	cmd := exec.Command("osascript", "-e", script)
-	output, err := cmd.Output()
+	script := a.scripts.JXA(`
+		if (!app.running() || app.playerState() === "stopped") return JSON.stringify(null);
+		return JSON.stringify(trackJSON(app.currentTrack.properties()));
+	`)
+	var track *model.Track
+	if err := a.runJSON(ctx, queryTimeout, script, &track); err != nil {
+		return nullTrack, fmt.Errorf("error getting current track: %w", err)
+	}
+	if track == nil { // not running or stopped, there is no current track
+		return nullTrack, nil
+	}
+
+	return *track, nil
+}
+
+// GetNowPlaying read the whole player state with a single script, so every field belong to the same track.
+func (a *appleMusicBridge) GetNowPlaying(ctx context.Context) (model.NowPlaying, error) {
+	nowPlaying := model.NowPlaying{
+		Track:  model.Track{Name: "No Track Playing"},
+		State:  model.PlayerNotRunning,
+		Repeat: model.RepeatOff,
+	}
+	script := a.scripts.JXA(`
+		if (!app.running()) return JSON.stringify(null);
+		const snapshot = {
+			state: app.playerState(),
+			volume: app.soundVolume(),
+			shuffle: app.shuffleEnabled(),
+			repeat: app.songRepeat(),
+		};
+		// there is no current track/playlist when stopped
+		try {
+			snapshot.track = trackJSON(app.currentTrack.properties());
+			snapshot.position = app.playerPosition();
+		} catch (e) {}
+		try {
+			const p = app.currentPlaylist;
+			const trackCount = p.tracks.length;
+			snapshot.playlist = {
+				id: p.persistentID(),
+				name: p.name(),
+				trackCount: trackCount,
+				// playlists have no modification date, those change with the tracks
+				stamp: [trackCount, p.duration(), p.size()].join(":"),
+			};
+		} catch (e) {}
+		return JSON.stringify(snapshot);
+	`)
+	var snapshot *model.NowPlaying
+	err := a.runJSON(ctx, queryTimeout, script, &snapshot)
+	if errors.Is(err, ErrAppNotRunning) {
+		return nowPlaying, nil
+	}
 	if err != nil {
-		if err.Error() == "exit status 1" { // "Apple Music is not running"
-			return nullTrack, nil
-		}
-		return nullTrack, fmt.Errorf("error getting current track: %v", err)
+		// the state is unknown, not "not running"
+		return model.NowPlaying{}, fmt.Errorf("error getting now playing: %w", err)
 	}
+	if snapshot == nil { // "Apple Music is not running"
+		return nowPlaying, nil
+	}
+	if snapshot.Track.Id == "" {
+		snapshot.Track = nowPlaying.Track
+	}
+	snapshot.State = normalizePlayerState(snapshot.State)
+	return *snapshot, nil
+}
 
-	track := a.appleTrackRecordMap2Track(a.parseAppleRecord(string(output)))
+func (a *appleMusicBridge) GetPlayerState(ctx context.Context) (model.PlayerState, error) {
+	script := a.scripts.JXA(`
+		if (!app.running()) return JSON.stringify("not running");
+		return JSON.stringify(app.playerState());
+	`)
+	var state model.PlayerState
+	if err := a.runJSON(ctx, queryTimeout, script, &state); err != nil {
+		return model.PlayerStopped, fmt.Errorf("error getting player state: %w", err)
+	}
+	return normalizePlayerState(state), nil
+}
 
-	return track, nil
+// normalizePlayerState fold the states of Music.app the UI does not care about
+func normalizePlayerState(state model.PlayerState) model.PlayerState {
+	switch state {
+	case "fast forwarding", "rewinding":
+		return model.PlayerPlaying
+	case model.PlayerNotRunning, model.PlayerStopped, model.PlayerPlaying, model.PlayerPaused:
+		return state
+	default:
+		return model.PlayerStopped
+	}
 }
 
-func (a *appleMusicBridge) PlayPause() tea.Cmd {
+func (a *appleMusicBridge) LaunchApp(ctx context.Context) tea.Cmd {
 	return func() tea.Msg {
-		cmd := exec.Command("osascript", "-e", fmt.Sprintf(`tell application "%s" to playpause`, a.appName))
-		if err := cmd.Run(); err != nil {
+		if _, err := a.run(ctx, launchTimeout, a.scripts.AppleScript(`tell application $APP to run`)); err != nil {
+			a.log(fmt.Sprintf("Error launching app: %v", err.Error()))
+			return err
+		}
+		return constant.EventPlayerStateChanged{}
+	}
+}
+
+func (a *appleMusicBridge) PlayPause(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		if _, err := a.run(ctx, controlTimeout, a.scripts.AppleScript(`tell application $APP to playpause`)); err != nil {
 			a.log(fmt.Sprintf("Error toggling play/pause: %v", err.Error()))
 			return err
 		}
-		return nil
+		return constant.EventPlayerStateChanged{}
 	}
 }
 
-func (a *appleMusicBridge) Play() tea.Cmd {
+func (a *appleMusicBridge) Play(ctx context.Context) tea.Cmd {
 	return func() tea.Msg {
-		cmd := exec.Command("osascript", "-e", fmt.Sprintf(`tell application "%s" to play`, a.appName))
-		if err := cmd.Run(); err != nil {
+		if _, err := a.run(ctx, controlTimeout, a.scripts.AppleScript(`tell application $APP to play`)); err != nil {
 			a.log(fmt.Sprintf("Error playing track: %v", err.Error()))
 			return err
 		}
-		return nil
+		return constant.EventPlayerStateChanged{}
 	}
 }
 
-func (a *appleMusicBridge) Pause() tea.Cmd {
+func (a *appleMusicBridge) Pause(ctx context.Context) tea.Cmd {
 	return func() tea.Msg {
-		cmd := exec.Command("osascript", "-e", fmt.Sprintf(`tell application "%s" to pause`, a.appName))
-		if err := cmd.Run(); err != nil {
+		if _, err := a.run(ctx, controlTimeout, a.scripts.AppleScript(`tell application $APP to pause`)); err != nil {
 			a.log(fmt.Sprintf("Error pausing track: %v", err.Error()))
 			return err
 		}
-		return nil
+		return constant.EventPlayerStateChanged{}
 	}
 }
 
-func (a *appleMusicBridge) NextTrack() tea.Cmd {
+func (a *appleMusicBridge) NextTrack(ctx context.Context) tea.Cmd {
 	return func() tea.Msg {
-		cmd := exec.Command("osascript", "-e", fmt.Sprintf(`tell application "%s" to next track`, a.appName))
-		if err := cmd.Run(); err != nil {
+		if _, err := a.run(ctx, controlTimeout, a.scripts.AppleScript(`tell application $APP to next track`)); err != nil {
 			a.log(fmt.Sprintf("Error skipping to next track: %v", err.Error()))
 			return err
 		}
@@ -156,10 +303,9 @@ func (a *appleMusicBridge) NextTrack() tea.Cmd {
 	}
 }
 
-func (a *appleMusicBridge) PreviousTrack() tea.Cmd {
+func (a *appleMusicBridge) PreviousTrack(ctx context.Context) tea.Cmd {
 	return func() tea.Msg {
-		cmd := exec.Command("osascript", "-e", fmt.Sprintf(`tell application "%s" to previous track`, a.appName))
-		if err := cmd.Run(); err != nil {
+		if _, err := a.run(ctx, controlTimeout, a.scripts.AppleScript(`tell application $APP to previous track`)); err != nil {
 			a.log(fmt.Sprintf("Error skipping to previous track: %v", err.Error()))
 			return err
 		}
@@ -168,14 +314,13 @@ func (a *appleMusicBridge) PreviousTrack() tea.Cmd {
 	}
 }
 
-func (a *appleMusicBridge) SetVolume(volume int) tea.Cmd {
+func (a *appleMusicBridge) SetVolume(ctx context.Context, volume int) tea.Cmd {
 	return func() tea.Msg {
 		if volume < 0 || volume > 100 {
 			return fmt.Errorf("volume must be between 0 and 100")
 		}
 
-		cmd := exec.Command("osascript", "-e", fmt.Sprintf(`tell application "%s" to set sound volume to %d`, a.appName, volume))
-		if err := cmd.Run(); err != nil {
+		if _, err := a.run(ctx, controlTimeout, a.scripts.AppleScript(`tell application $APP to set sound volume to (item 1 of argv) as integer`, strconv.Itoa(volume))); err != nil {
 			a.log(fmt.Sprintf("Error setting volume: %v", err.Error()))
 			return err
 		}
@@ -183,17 +328,15 @@ func (a *appleMusicBridge) SetVolume(volume int) tea.Cmd {
 	}
 }
 
-func (a *appleMusicBridge) IncreaseVolume() tea.Cmd {
+func (a *appleMusicBridge) IncreaseVolume(ctx context.Context) tea.Cmd {
 	return func() tea.Msg {
-		script := fmt.Sprintf(`
-		tell application "%s"
+		script := a.scripts.AppleScript(`
+		tell application $APP
 			set currentVolume to sound volume
 			set sound volume to (currentVolume + 10)
 		end tell
-		`, a.appName)
-		cmd := exec.Command("osascript", "-e", script)
-
-		if err := cmd.Run(); err != nil {
+		`)
+		if _, err := a.run(ctx, controlTimeout, script); err != nil {
 			a.log(fmt.Sprintf("Error increasing volume: %v", err.Error()))
 			return err
 		}
@@ -201,17 +344,15 @@ func (a *appleMusicBridge) IncreaseVolume() tea.Cmd {
 	}
 }
 
-func (a *appleMusicBridge) DecreaseVolume() tea.Cmd {
+func (a *appleMusicBridge) DecreaseVolume(ctx context.Context) tea.Cmd {
 	return func() tea.Msg {
-		script := fmt.Sprintf(`
-		tell application "%s"
+		script := a.scripts.AppleScript(`
+		tell application $APP
 			set currentVolume to sound volume
 			set sound volume to (currentVolume - 10)
 		end tell
-		`, a.appName)
-		cmd := exec.Command("osascript", "-e", script)
-
-		if err := cmd.Run(); err != nil {
+		`)
+		if _, err := a.run(ctx, controlTimeout, script); err != nil {
 			a.log(fmt.Sprintf("Error decreasing volume: %v", err.Error()))
 			return err
 		}
@@ -219,10 +360,11 @@ func (a *appleMusicBridge) DecreaseVolume() tea.Cmd {
 	}
 }
 
-func (a *appleMusicBridge) PlayPlaylist(playlistName string) tea.Cmd {
+func (a *appleMusicBridge) PlayPlaylist(ctx context.Context, playlistName string) tea.Cmd {
 	return func() tea.Msg {
-		cmd := exec.Command("osascript", "-e", fmt.Sprintf(`tell application "%s" to play playlist "%s"`, a.appName, playlistName))
-		if err := cmd.Run(); err != nil {
+		script := a.scripts.AppleScript(`tell application $APP to play playlist (item 1 of argv)`, playlistName)
+		script.Names = []int{0}
+		if _, err := a.run(ctx, controlTimeout, script); err != nil {
 			a.log(fmt.Sprintf("Error playing playlist '%s': %v", playlistName, err.Error()))
 			return err
 		}
@@ -230,31 +372,22 @@ func (a *appleMusicBridge) PlayPlaylist(playlistName string) tea.Cmd {
 	}
 }
 
-func (a *appleMusicBridge) PlayTrackById(id string) tea.Cmd {
+// PlayTrackById play the track from the library, playback continue through the library.
+// Use PlayTrackInPlaylist to keep the playlist the track is played from.
+func (a *appleMusicBridge) PlayTrackById(ctx context.Context, id string) tea.Cmd {
 	return func() tea.Msg {
-		script := fmt.Sprintf(`set targetID to "%s"
+		script := a.scripts.AppleScript(`set targetID to item 1 of argv
 			set foundTrack to missing value
-			tell application "%s"
-				repeat with p in every playlist
-					try
-						set foundTrack to (first track of p whose persistent ID is targetID)
-						if foundTrack is not missing value then
-							exit repeat
-						end if
-					end try
-				end repeat
-
-				if foundTrack is not missing value then
-					play foundTrack
-					return "播放「" & (get name of foundTrack) & "」！"
-				else
-					return "錯誤：找不到 persistent ID 為 " & targetID & " 的歌曲。"
-				end if
-			end tell`, id, a.appName)
+			tell application $APP
+				try
+					set foundTrack to (first track of library playlist 1 whose persistent ID is targetID)
+				end try
+				if foundTrack is missing value then my trackNotFound(targetID)
 
-		cmd := exec.Command("osascript", "-e", script)
+				play foundTrack
+			end tell`, id)
 
-		if err := cmd.Run(); err != nil {
+		if _, err := a.run(ctx, controlTimeout, script); err != nil {
 			a.log(fmt.Sprintf("Error play track byid: %v", err))
 			return err
 		}
@@ -263,9 +396,39 @@ func (a *appleMusicBridge) PlayTrackById(id string) tea.Cmd {
 	}
 }
 
-func (a *appleMusicBridge) FavoriteCurrentTrack() tea.Cmd {
+// PlayTrackInPlaylist play the track from the playlist, so playback continue through the playlist.
+func (a *appleMusicBridge) PlayTrackInPlaylist(ctx context.Context, playlistId, trackId string) tea.Cmd {
 	return func() tea.Msg {
-		cmd := exec.Command("osascript", "-e", fmt.Sprintf(`tell application "%s"
+		script := a.scripts.AppleScript(`set playlistID to item 1 of argv
+			set targetID to item 2 of argv
+			tell application $APP
+				set foundPlaylist to missing value
+				try
+					set foundPlaylist to (first playlist whose persistent ID is playlistID)
+				end try
+				if foundPlaylist is missing value then my playlistNotFound(playlistID)
+
+				set foundTrack to missing value
+				try
+					set foundTrack to (first track of foundPlaylist whose persistent ID is targetID)
+				end try
+				if foundTrack is missing value then my trackNotFound(targetID)
+
+				play foundTrack
+			end tell`, playlistId, trackId)
+
+		if _, err := a.run(ctx, controlTimeout, script); err != nil {
+			a.log(fmt.Sprintf("Error play track in playlist: %v", err))
+			return err
+		}
+
+		return constant.EventTrackChanged{}
+	}
+}
+
+func (a *appleMusicBridge) FavoriteCurrentTrack(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		output, err := a.run(ctx, controlTimeout, a.scripts.AppleScript(`tell application $APP
 			set aTrack to current track
 			set persistentId to persistent ID of aTrack
 			if favorited of aTrack then
@@ -275,46 +438,41 @@ func (a *appleMusicBridge) FavoriteCurrentTrack() tea.Cmd {
 			end if
 		end tell
 		return persistentId
-		`, a.appName))
-		output, err := cmd.Output()
+		`))
 		if err != nil {
 			a.log(fmt.Sprintf("Error favoriting track: %v", err.Error()))
 			return err
 		}
 
-		return constant.EventFavoriteTrackId(strings.TrimSpace(string(output)))
+		return constant.EventFavoriteTrackId(strings.TrimSpace(output))
 	}
 }
 
-func (a *appleMusicBridge) FavoriteTrackByTrackId(id string) tea.Cmd {
+func (a *appleMusicBridge) FavoriteTrackByTrackId(ctx context.Context, id string) tea.Cmd {
 	return func() tea.Msg {
-		script := fmt.Sprintf(`set targetID to "%s"
+		script := a.scripts.AppleScript(`set targetID to item 1 of argv
 			set foundTrack to missing value
-			tell application "%s"
-				repeat with p in every playlist
+			tell application $APP
+				-- the current playlist has the tracks not added to the library
+				try
+					set foundTrack to (first track of current playlist whose persistent ID is targetID)
+				end try
+				if foundTrack is missing value then
 					try
-						set foundTrack to (first track of p whose persistent ID is targetID)
-						if foundTrack is not missing value then
-							exit repeat
-						end if
+						set foundTrack to (first track of library playlist 1 whose persistent ID is targetID)
 					end try
-				end repeat
+				end if
 
-				if foundTrack is not missing value then
-					if favorited of foundTrack then
-						set favorited of foundTrack to false
-					else
-						set favorited of foundTrack to true
-					end if
-					return "成功將歌曲「" & (get name of foundTrack) & "」加入收藏！"
+				if foundTrack is missing value then my trackNotFound(targetID)
+
+				if favorited of foundTrack then
+					set favorited of foundTrack to false
 				else
-					return "錯誤：找不到 persistent ID 為 " & targetID & " 的歌曲。"
+					set favorited of foundTrack to true
 				end if
-			end tell`, id, a.appName)
-
-		cmd := exec.Command("osascript", "-e", script)
+			end tell`, id)
 
-		if err := cmd.Run(); err != nil {
+		if _, err := a.run(ctx, controlTimeout, script); err != nil {
 			a.log(fmt.Sprintf("Error favoriting track byid: %v", err))
 			return err
 		}
@@ -323,117 +481,517 @@ func (a *appleMusicBridge) FavoriteTrackByTrackId(id string) tea.Cmd {
 	}
 }
 
-func (a *appleMusicBridge) GetPlaylists() ([]string, error) {
-	cmd := exec.Command("osascript", "-e", fmt.Sprintf(`tell application "%s" to set playlistNames to name of every playlist`, a.appName))
-	output, err := cmd.Output()
-	if err != nil {
-		a.log(fmt.Sprintf("Error getting playlists: %v", err.Error()))
-		return nil, err
+// SetRating set the 0-100 rating of the track, 20 per star.
+func (a *appleMusicBridge) SetRating(ctx context.Context, trackId string, rating int) tea.Cmd {
+	return func() tea.Msg {
+		if rating < 0 || rating > 100 {
+			return fmt.Errorf("invalid rating: %d", rating)
+		}
+		script := a.scripts.AppleScript(`set targetID to item 1 of argv
+			set targetRating to (item 2 of argv) as integer
+			tell application $APP
+				set foundTrack to missing value
+				try
+					set foundTrack to (first track of current playlist whose persistent ID is targetID)
+				end try
+				if foundTrack is missing value then
+					try
+						set foundTrack to (first track of library playlist 1 whose persistent ID is targetID)
+					end try
+				end if
+				if foundTrack is missing value then my trackNotFound(targetID)
+				set rating of foundTrack to targetRating
+			end tell`, trackId, strconv.Itoa(rating))
+		if _, err := a.run(ctx, controlTimeout, script); err != nil {
+			a.log(fmt.Sprintf("Error setting rating: %v", err.Error()))
+			return err
+		}
+		return constant.EventTrackRatingChanged{TrackId: trackId, Rating: rating}
 	}
+}
 
-	// Convert output to string and split by comma
-	playlists := strings.Split(string(output), ", ")
-	playlistNames := []string{}
-	for _, name := range playlists {
-		if strings.TrimSpace(name) != "" {
-			playlistNames = append(playlistNames, strings.TrimSpace(name))
+func (a *appleMusicBridge) SetDisliked(ctx context.Context, trackId string, disliked bool) tea.Cmd {
+	return func() tea.Msg {
+		script := a.scripts.AppleScript(`set targetID to item 1 of argv
+			set targetDisliked to (item 2 of argv) as boolean
+			tell application $APP
+				set foundTrack to missing value
+				try
+					set foundTrack to (first track of current playlist whose persistent ID is targetID)
+				end try
+				if foundTrack is missing value then
+					try
+						set foundTrack to (first track of library playlist 1 whose persistent ID is targetID)
+					end try
+				end if
+				if foundTrack is missing value then my trackNotFound(targetID)
+				set disliked of foundTrack to targetDisliked
+			end tell`, trackId, strconv.FormatBool(disliked))
+		if _, err := a.run(ctx, controlTimeout, script); err != nil {
+			a.log(fmt.Sprintf("Error setting disliked: %v", err.Error()))
+			return err
 		}
+		return constant.EventTrackDislikedChanged{TrackId: trackId, Disliked: disliked}
 	}
-	return playlistNames, nil
 }
 
-// FIXME: if is big list, it will be slow
-func (a *appleMusicBridge) GetCurrentPlaylist() (model.Playlist, error) {
-	cmd := exec.Command("osascript", "-e", fmt.Sprintf(`
-	tell application "%s"
-		set currentList to current playlist
-		set output to {}
-		repeat with t in tracks of currentList
-			set end of output to properties of t
-			set end of output to "######"
-		end repeat
-	end tell
-	return output
-	`, a.appName))
+func (a *appleMusicBridge) SetShuffle(ctx context.Context, enabled bool) tea.Cmd {
+	return func() tea.Msg {
+		script := a.scripts.AppleScript(`tell application $APP to set shuffle enabled to (item 1 of argv) as boolean`, strconv.FormatBool(enabled))
+		if _, err := a.run(ctx, controlTimeout, script); err != nil {
+			a.log(fmt.Sprintf("Error setting shuffle: %v", err.Error()))
+			return err
+		}
+		return constant.EventUpdateShuffle(enabled)
+	}
+}
+
+func (a *appleMusicBridge) SetRepeat(ctx context.Context, mode model.RepeatMode) tea.Cmd {
+	return func() tea.Msg {
+		if !mode.Valid() {
+			return fmt.Errorf("unknown repeat mode: %s", mode)
+		}
+
+		// song repeat is an enumeration, it can not be set from a string directly
+		script := a.scripts.AppleScript(`
+		set mode to item 1 of argv
+		tell application $APP
+			if mode is "one" then
+				set song repeat to one
+			else if mode is "all" then
+				set song repeat to all
+			else
+				set song repeat to off
+			end if
+		end tell
+		`, string(mode))
+		if _, err := a.run(ctx, controlTimeout, script); err != nil {
+			a.log(fmt.Sprintf("Error setting repeat: %v", err.Error()))
+			return err
+		}
+		return constant.EventUpdateRepeat(mode)
+	}
+}
+
+func (a *appleMusicBridge) SetPlayerPosition(ctx context.Context, seconds int) tea.Cmd {
+	return func() tea.Msg {
+		if seconds < 0 {
+			return fmt.Errorf("position must not be negative")
+		}
 
-	// set end of output to (name of t & " - " & artist of t)
-	output, err := cmd.Output()
+		script := a.scripts.AppleScript(`tell application $APP to set player position to (item 1 of argv) as integer`, strconv.Itoa(seconds))
+		if _, err := a.run(ctx, controlTimeout, script); err != nil {
+			a.log(fmt.Sprintf("Error setting player position: %v", err.Error()))
+			return err
+		}
+		return constant.EventPlayerPositionChanged(seconds)
+	}
+}
+
+// Seek move the player position by deltaSeconds, a negative delta rewind.
+func (a *appleMusicBridge) Seek(ctx context.Context, deltaSeconds int) tea.Cmd {
+	return func() tea.Msg {
+		script := a.scripts.AppleScript(`
+		set delta to (item 1 of argv) as integer
+		tell application $APP
+			set newPosition to (player position) + delta
+			if newPosition < 0 then set newPosition to 0
+			set player position to newPosition
+			return player position
+		end tell
+		`, strconv.Itoa(deltaSeconds))
+		output, err := a.run(ctx, controlTimeout, script)
+		if err != nil {
+			a.log(fmt.Sprintf("Error seeking: %v", err.Error()))
+			return err
+		}
+
+		position, err := strconv.ParseFloat(strings.TrimSpace(output), 64)
+		if err != nil {
+			return &ParseError{Output: output, Err: err}
+		}
+		return constant.EventPlayerPositionChanged(int(position))
+	}
+}
+
+func (a *appleMusicBridge) GetShuffle(ctx context.Context) (bool, error) {
+	output, err := a.run(ctx, queryTimeout, a.scripts.AppleScript(`tell application $APP to return shuffle enabled`))
+	if err != nil {
+		return false, fmt.Errorf("error getting shuffle: %w", err)
+	}
+
+	enabled, err := strconv.ParseBool(strings.TrimSpace(output))
+	if err != nil {
+		return false, &ParseError{Output: output, Err: err}
+	}
+	return enabled, nil
+}
+
+func (a *appleMusicBridge) GetRepeat(ctx context.Context) (model.RepeatMode, error) {
+	output, err := a.run(ctx, queryTimeout, a.scripts.AppleScript(`tell application $APP to return (song repeat as text)`))
 	if err != nil {
-		return model.Playlist{}, fmt.Errorf("error getting current playlist: %v", err)
+		return model.RepeatOff, fmt.Errorf("error getting repeat: %w", err)
+	}
+
+	mode := model.RepeatMode(strings.TrimSpace(output))
+	if !mode.Valid() {
+		return model.RepeatOff, &ParseError{Output: output, Err: fmt.Errorf("unknown repeat mode: %s", mode)}
 	}
+	return mode, nil
+}
 
-	trackStrList := strings.Split(string(output), "######")
-	if len(trackStrList) > 0 {
-		trackStrList = trackStrList[:len(trackStrList)-1] // Remove the last empty string if it exists
+func (a *appleMusicBridge) GetPlaylists(ctx context.Context) ([]model.Playlist, error) {
+	playlists := []model.Playlist{}
+	err := a.runJSON(ctx, libraryTimeout, a.scripts.JXA(`
+		const playlists = app.playlists;
+		const ids = playlists.persistentID();
+		const names = playlists.name();
+		return JSON.stringify(ids.map((id, i) => ({ id: id, name: names[i] })));
+	`), &playlists)
+	if err != nil {
+		a.log(fmt.Sprintf("Error getting playlists: %v", err.Error()))
+		return nil, err
 	}
+	return playlists, nil
+}
+
+// GetUserPlaylists return the playlists tracks can be added to, smart and special playlists are left out.
+func (a *appleMusicBridge) GetUserPlaylists(ctx context.Context) ([]model.Playlist, error) {
+	playlists := []model.Playlist{}
+	err := a.runJSON(ctx, libraryTimeout, a.scripts.JXA(`
+		const playlists = app.userPlaylists;
+		const ids = playlists.persistentID();
+		const names = playlists.name();
+		const smarts = playlists.smart();
+		const kinds = playlists.specialKind();
+		const out = [];
+		ids.forEach((id, i) => {
+			if (!smarts[i] && kinds[i] === "none") out.push({ id: id, name: names[i] });
+		});
+		return JSON.stringify(out);
+	`), &playlists)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user playlists: %w", err)
+	}
+	return playlists, nil
+}
+
+func (a *appleMusicBridge) GetCurrentPlaylistInfo(ctx context.Context) (model.Playlist, error) {
 	playlist := model.Playlist{}
-	for _, trackStr := range trackStrList {
-		playlist.Tracks = append(playlist.Tracks,
-			a.appleTrackRecordMap2Track(a.parseAppleRecord(trackStr)),
-		)
+	err := a.runJSON(ctx, queryTimeout, a.scripts.JXA(`
+		const p = app.currentPlaylist;
+		return JSON.stringify({
+			id: p.persistentID(),
+			name: p.name(),
+			trackCount: p.tracks.length,
+		});
+	`), &playlist)
+	if err != nil {
+		return model.Playlist{}, fmt.Errorf("error getting current playlist: %w", err)
 	}
 
 	return playlist, nil
 }
 
-func (a *appleMusicBridge) GetPlayerPosition() (int, error) {
-	cmd := exec.Command("osascript", "-e", fmt.Sprintf(`
-		tell application "%s"
+// GetCurrentPlaylistTracks return up to limit tracks of the current playlist starting at offset,
+// with only the fields needed to list them. Use GetTrackById for the full properties.
+func (a *appleMusicBridge) GetCurrentPlaylistTracks(ctx context.Context, offset, limit int) ([]model.Track, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, fmt.Errorf("invalid page: offset %d, limit %d", offset, limit)
+	}
+
+	// a range reference fetch one column of the whole page in a single Apple event,
+	// JXA has no range reference so this one stay in AppleScript
+	script := a.scripts.AppleScript(`
+		set startIndex to ((item 1 of argv) as integer) + 1
+		set endIndex to (item 2 of argv) as integer
+		tell application $APP
+			set p to current playlist
+			set trackCount to count of tracks of p
+			if endIndex > trackCount then set endIndex to trackCount
+			if startIndex > endIndex then return "[]"
+			set ids to persistent ID of tracks startIndex thru endIndex of p
+			set names to name of tracks startIndex thru endIndex of p
+			set artists to artist of tracks startIndex thru endIndex of p
+			set favs to favorited of tracks startIndex thru endIndex of p
+			set ratings to rating of tracks startIndex thru endIndex of p
+			set dislikes to disliked of tracks startIndex thru endIndex of p
+		end tell
+		set out to {}
+		repeat with i from 1 to count of ids
+			set end of out to "{\"id\":" & my jsonString(item i of ids) & ¬
+				",\"name\":" & my jsonString(item i of names) & ¬
+				",\"artist\":" & my jsonString(item i of artists) & ¬
+				",\"favorited\":" & ((item i of favs) as text) & ¬
+				",\"rating\":" & ((item i of ratings) as text) & ¬
+				",\"disliked\":" & ((item i of dislikes) as text) & "}"
+		end repeat
+		return "[" & my joinText(out, ",") & "]"
+	`, strconv.Itoa(offset), strconv.Itoa(offset+limit))
+
+	tracks := []model.Track{}
+	if err := a.runJSON(ctx, libraryTimeout, script, &tracks); err != nil {
+		return nil, fmt.Errorf("error getting current playlist tracks: %w", err)
+	}
+	return tracks, nil
+}
+
+func (a *appleMusicBridge) GetTrackById(ctx context.Context, id string) (model.Track, error) {
+	script := a.scripts.JXA(`
+		const sources = [app.currentPlaylist, app.libraryPlaylists[0]];
+		for (const source of sources) {
+			try {
+				const found = source.tracks.whose({ persistentID: argv[0] })();
+				if (found.length > 0) return JSON.stringify(trackJSON(found[0].properties()));
+			} catch (e) {}
+		}
+		return JSON.stringify(null);
+	`, id)
+	var track *model.Track
+	if err := a.runJSON(ctx, libraryTimeout, script, &track); err != nil {
+		return model.Track{}, fmt.Errorf("error getting track: %w", err)
+	}
+	if track == nil {
+		return model.Track{}, fmt.Errorf("%w: %s", ErrTrackNotFound, id)
+	}
+	return *track, nil
+}
+
+// SearchLibrary search the whole library with Music's search command, at most searchLimit tracks are returned.
+func (a *appleMusicBridge) SearchLibrary(ctx context.Context, query string, field model.SearchField) ([]model.Track, error) {
+	if !field.Valid() {
+		return nil, fmt.Errorf("unknown search field: %s", field)
+	}
+	if strings.TrimSpace(query) == "" {
+		return []model.Track{}, nil
+	}
+
+	// only is an enumeration, it can not be set from a string directly
+	script := a.scripts.AppleScript(`
+		set q to item 1 of argv
+		set field to item 2 of argv
+		set maxCount to (item 3 of argv) as integer
+		set out to {}
+		tell application $APP
+			if field is "songs" then
+				set found to search library playlist 1 for q only songs
+			else if field is "artists" then
+				set found to search library playlist 1 for q only artists
+			else if field is "albums" then
+				set found to search library playlist 1 for q only albums
+			else
+				set found to search library playlist 1 for q
+			end if
+			repeat with t in found
+				if (count of out) ≥ maxCount then exit repeat
+				set end of out to "{\"id\":" & my jsonString(persistent ID of t) & ¬
+					",\"name\":" & my jsonString(name of t) & ¬
+					",\"artist\":" & my jsonString(artist of t) & ¬
+					",\"album\":" & my jsonString(album of t) & ¬
+					",\"favorited\":" & ((favorited of t) as text) & ¬
+					",\"rating\":" & ((rating of t) as text) & ¬
+					",\"disliked\":" & ((disliked of t) as text) & "}"
+			end repeat
+		end tell
+		return "[" & my joinText(out, ",") & "]"
+	`, query, string(field), strconv.Itoa(searchLimit))
+	script.Names = []int{0}
+
+	tracks := []model.Track{}
+	if err := a.runJSON(ctx, libraryTimeout, script, &tracks); err != nil {
+		return nil, fmt.Errorf("error searching library: %w", err)
+	}
+	return tracks, nil
+}
+
+func (a *appleMusicBridge) GetPlayerPosition(ctx context.Context) (int, error) {
+	output, err := a.run(ctx, queryTimeout, a.scripts.AppleScript(`
+		tell application $APP
 			set playerPosition to player position
 		end tell
 		return playerPosition
-	`, a.appName))
-
-	output, err := cmd.Output()
+	`))
 	if err != nil {
-		return 0, fmt.Errorf("error getting player position: %v", err)
+		return 0, fmt.Errorf("error getting player position: %w", err)
 	}
 
-	position, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+	position, err := strconv.ParseFloat(strings.TrimSpace(output), 64)
 	if err != nil {
-		return 0, fmt.Errorf("error parsing player position: %v", err)
+		return 0, &ParseError{Output: output, Err: err}
 	}
 
 	return int(position), nil
 }
 
-// TODO: cache album img
-// TODO: check img exist
-func (a *appleMusicBridge) GetCurrentAlbum(width, height int) (string, error) {
-	width *= 2
-	filePath := "/tmp/cover.jpg"
-	cmd := exec.Command("osascript", "-e", fmt.Sprintf(`
-		set tmpPath to POSIX file "%s"
-		tell application "%s"
-			set aTrack to current track
-			set ac to count of artworks of aTrack
-			if ac = 0 then return "No Artwork"
-			set artData to data of artwork 1 of aTrack
-		end tell
-		set outFile to open for access tmpPath with write permission
-		try
-			set eof outFile to 0
-			write artData to outFile
-		end try
-		close access outFile
-		return POSIX path of tmpPath
-		EOF
-	`, filePath, a.appName))
-
-	err := cmd.Run()
-	if err != nil {
-		spew.Fdump(a.dump, "Error getting current album artwork:", err)
-		return "", fmt.Errorf("error getting current album artwork: %v", err)
+// GetArtwork return the path of the artwork of track in the artwork store, or an empty path when it has none.
+// Music is only asked for artworks which are not stored yet.
+func (a *appleMusicBridge) GetArtwork(ctx context.Context, track model.Track) (string, error) {
+	key := artwork.Key(track)
+	if key == "" {
+		return "", nil
+	}
+	if path, ok := a.artworks.Lookup(key); ok {
+		return path, nil
 	}
 
-	sizeOpt := imeji.WithResize(width, height)
-	text, err := imeji.FileString(
-		filePath,
-		sizeOpt,
-		imeji.WithTrueColor(), // 24-bit
-	)
+	path, err := a.artworks.Put(key, func(path string) error {
+		script := a.scripts.AppleScript(`
+			set outPath to POSIX file (item 1 of argv)
+			set trackId to item 2 of argv
+			tell application $APP
+				set aTrack to missing value
+				try
+					if persistent ID of current track is trackId then set aTrack to current track
+				end try
+				if aTrack is missing value then
+					try
+						set aTrack to first track of library playlist 1 whose persistent ID is trackId
+					end try
+				end if
+				if aTrack is missing value then my trackNotFound(trackId)
+				if (count of artworks of aTrack) = 0 then return "No Artwork"
+				set artData to data of artwork 1 of aTrack
+			end tell
+			set outFile to open for access outPath with write permission
+			try
+				set eof outFile to 0
+				write artData to outFile
+			end try
+			close access outFile
+			return "OK"
+		`, path, track.Id)
+		script.Output = path
+		out, err := a.run(ctx, libraryTimeout, script)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(out) == "No Artwork" {
+			return errNoArtwork
+		}
+		return nil
+	})
+	if errors.Is(err, errNoArtwork) {
+		return "", nil
+	}
 	if err != nil {
-		return "", fmt.Errorf("imeji: %w", err)
+		a.log(fmt.Sprintf("Error getting artwork: %v", err.Error()))
+		return "", fmt.Errorf("error getting artwork: %w", err)
+	}
+	return path, nil
+}
+
+// ======= playlist management
+
+func (a *appleMusicBridge) CreatePlaylist(ctx context.Context, name string) tea.Cmd {
+	return func() tea.Msg {
+		script := a.scripts.AppleScript(`tell application $APP
+			set p to make new user playlist with properties {name:(item 1 of argv)}
+			return persistent ID of p
+		end tell`, name)
+		script.Names = []int{0}
+		output, err := a.run(ctx, libraryTimeout, script)
+		if err != nil {
+			a.log(fmt.Sprintf("Error creating playlist: %v", err.Error()))
+			return err
+		}
+		return constant.EventPlaylistCreated(model.Playlist{Id: strings.TrimSpace(output), Name: name})
+	}
+}
+
+func (a *appleMusicBridge) RenamePlaylist(ctx context.Context, playlistId, name string) tea.Cmd {
+	return func() tea.Msg {
+		script := a.scripts.AppleScript(`tell application $APP
+			set name of my findUserPlaylist(item 1 of argv) to (item 2 of argv)
+		end tell`, playlistId, name)
+		script.Names = []int{1}
+		if _, err := a.run(ctx, libraryTimeout, script); err != nil {
+			a.log(fmt.Sprintf("Error renaming playlist: %v", err.Error()))
+			return err
+		}
+		return constant.EventPlaylistsChanged{}
+	}
+}
+
+func (a *appleMusicBridge) DeletePlaylist(ctx context.Context, playlistId string) tea.Cmd {
+	return func() tea.Msg {
+		script := a.scripts.AppleScript(`tell application $APP
+			delete my findUserPlaylist(item 1 of argv)
+		end tell`, playlistId)
+		if _, err := a.run(ctx, libraryTimeout, script); err != nil {
+			a.log(fmt.Sprintf("Error deleting playlist: %v", err.Error()))
+			return err
+		}
+		return constant.EventPlaylistsChanged{}
+	}
+}
+
+// AddTracksToPlaylist append the library tracks to the end of the user playlist.
+func (a *appleMusicBridge) AddTracksToPlaylist(ctx context.Context, playlistId string, trackIds []string) tea.Cmd {
+	return func() tea.Msg {
+		script := a.scripts.AppleScript(`tell application $APP
+			set p to my findUserPlaylist(item 1 of argv)
+			repeat with i from 2 to count of argv
+				duplicate my findLibraryTrack(item i of argv) to p
+			end repeat
+		end tell`, append([]string{playlistId}, trackIds...)...)
+		if _, err := a.run(ctx, libraryTimeout, script); err != nil {
+			a.log(fmt.Sprintf("Error adding tracks to playlist: %v", err.Error()))
+			return err
+		}
+		return constant.EventPlaylistChanged(playlistId)
+	}
+}
+
+// RemoveTracksFromPlaylist remove the tracks from the user playlist, they stay in the library.
+func (a *appleMusicBridge) RemoveTracksFromPlaylist(ctx context.Context, playlistId string, trackIds []string) tea.Cmd {
+	return func() tea.Msg {
+		script := a.scripts.AppleScript(`tell application $APP
+			set p to my findUserPlaylist(item 1 of argv)
+			repeat with i from 2 to count of argv
+				delete (every track of p whose persistent ID is (item i of argv))
+			end repeat
+		end tell`, append([]string{playlistId}, trackIds...)...)
+		if _, err := a.run(ctx, libraryTimeout, script); err != nil {
+			a.log(fmt.Sprintf("Error removing tracks from playlist: %v", err.Error()))
+			return err
+		}
+		return constant.EventPlaylistChanged(playlistId)
+	}
+}
+
+// ReorderPlaylist put the tracks of the user playlist in the order of trackIds, which should hold all of them.
+// Music can not move tracks inside a playlist, so the playlist is emptied and filled again.
+func (a *appleMusicBridge) ReorderPlaylist(ctx context.Context, playlistId string, trackIds []string) tea.Cmd {
+	return func() tea.Msg {
+		script := a.scripts.AppleScript(`tell application $APP
+			set p to my findUserPlaylist(item 1 of argv)
+			-- every track deleted is added back, so argv must hold every track of p
+			set remaining to persistent ID of every track of p
+			if (count of remaining) is not (count of argv) - 1 then my playlistChanged(item 1 of argv)
+			repeat with i from 2 to count of argv
+				set found to false
+				repeat with j from 1 to count of remaining
+					if item j of remaining is (item i of argv) then
+						set item j of remaining to missing value
+						set found to true
+						exit repeat
+					end if
+				end repeat
+				if not found then my playlistChanged(item 1 of argv)
+			end repeat
+			set ordered to {}
+			repeat with i from 2 to count of argv
+				set end of ordered to my findLibraryTrack(item i of argv)
+			end repeat
+			delete every track of p
+			repeat with t in ordered
+				duplicate t to p
+			end repeat
+		end tell`, append([]string{playlistId}, trackIds...)...)
+		if _, err := a.run(ctx, libraryTimeout, script); err != nil {
+			a.log(fmt.Sprintf("Error reordering playlist: %v", err.Error()))
+			return err
+		}
+		return constant.EventPlaylistChanged(playlistId)
 	}
-	return text, nil
 }
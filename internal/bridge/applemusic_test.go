@@ -0,0 +1,155 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"io"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"reflect"
+	"testing"
+)
+
+func newTestAppleMusicBridge(runner ScriptRunner) PlayerBridge {
+	return NewAppleMusicBridge(io.Discard, runner, nil)
+}
+
+func TestGetNowPlaying(t *testing.T) {
+	snapshot := `{
+		"state": "fast forwarding",
+		"volume": 40,
+		"shuffle": true,
+		"repeat": "all",
+		"position": 12.5,
+		"track": {"id": "T1", "name": "Song", "artist": "Band", "duration": 200, "favorited": true, "rating": 60},
+		"playlist": {"id": "P1", "name": "Mix", "trackCount": 3, "stamp": "3:600:1024"}
+	}`
+	runner := NewFakeScriptRunner().OnStdout(snapshot+"\n", "snapshot.playlist")
+	got, err := newTestAppleMusicBridge(runner).GetNowPlaying(context.Background())
+	if err != nil {
+		t.Fatalf("GetNowPlaying: %v", err)
+	}
+
+	want := model.NowPlaying{
+		Track:    model.Track{Id: "T1", Name: "Song", Artist: "Band", Duration: 200, Favorited: true, Rating: 60},
+		State:    model.PlayerPlaying,
+		Position: 12.5,
+		Volume:   40,
+		Shuffle:  true,
+		Repeat:   model.RepeatAll,
+		Playlist: model.Playlist{Id: "P1", Name: "Mix", TrackCount: 3, Stamp: "3:600:1024"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetNowPlaying\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestGetNowPlayingStopped(t *testing.T) {
+	runner := NewFakeScriptRunner().OnStdout(`{"state":"stopped","volume":50,"shuffle":false,"repeat":"off"}`, "snapshot.playlist")
+	got, err := newTestAppleMusicBridge(runner).GetNowPlaying(context.Background())
+	if err != nil {
+		t.Fatalf("GetNowPlaying: %v", err)
+	}
+	if got.State != model.PlayerStopped || got.Track.Name != "No Track Playing" || got.Playlist.Id != "" {
+		t.Errorf("GetNowPlaying = %+v, want stopped without track nor playlist", got)
+	}
+}
+
+func TestGetNowPlayingErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		result    ScriptResult
+		wantState model.PlayerState
+		wantErr   error
+	}{
+		{
+			name:      "not running",
+			result:    ScriptResult{Stdout: "null\n"},
+			wantState: model.PlayerNotRunning,
+		},
+		{
+			name:      "quit while asked",
+			result:    ScriptResult{ExitCode: 1, Stderr: "execution error: Error: Application isn't running. (-600)\n"},
+			wantState: model.PlayerNotRunning,
+		},
+		{
+			name:    "permission denied",
+			result:  ScriptResult{ExitCode: 1, Stderr: "execution error: Error: Not authorized to send Apple events to Music. (-1743)\n"},
+			wantErr: ErrPermissionDenied,
+		},
+		{
+			name:    "apple event timeout",
+			result:  ScriptResult{ExitCode: 1, Stderr: "execution error: Error: AppleEvent timed out. (-1712)\n"},
+			wantErr: ErrTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := NewFakeScriptRunner().On(tt.result, "snapshot.playlist")
+			got, err := newTestAppleMusicBridge(runner).GetNowPlaying(context.Background())
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("GetNowPlaying error = %v, want %v", err, tt.wantErr)
+			}
+			// a failed poll must not pass for a closed player
+			if got.State != tt.wantState {
+				t.Errorf("GetNowPlaying state = %q, want %q", got.State, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestGetNowPlayingParseError(t *testing.T) {
+	runner := NewFakeScriptRunner().OnStdout("not json", "snapshot.playlist")
+	_, err := newTestAppleMusicBridge(runner).GetNowPlaying(context.Background())
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("GetNowPlaying error = %v, want a ParseError", err)
+	}
+	if parseErr.Output != "not json" {
+		t.Errorf("ParseError.Output = %q", parseErr.Output)
+	}
+}
+
+func TestGetCurrentPlaylistTracks(t *testing.T) {
+	// jsonString escape the control characters other than CR, LF and tab as \u00XX
+	page := `[{"id":"A","name":"One","artist":"X\u0007\u001f","favorited":true,"rating":0,"disliked":false},` +
+		`{"id":"B","name":"Two \"live\"","artist":"Y","favorited":false,"rating":80,"disliked":true}]`
+	runner := NewFakeScriptRunner().OnStdout(page+"\n", "set p to current playlist")
+	got, err := newTestAppleMusicBridge(runner).GetCurrentPlaylistTracks(context.Background(), 10, 5)
+	if err != nil {
+		t.Fatalf("GetCurrentPlaylistTracks: %v", err)
+	}
+
+	want := []model.Track{
+		{Id: "A", Name: "One", Artist: "X\a\x1f", Favorited: true},
+		{Id: "B", Name: `Two "live"`, Artist: "Y", Rating: 80, Disliked: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetCurrentPlaylistTracks\n got %+v\nwant %+v", got, want)
+	}
+
+	// the page is passed as argv: the 0-based offset and the end of the page
+	calls := runner.Calls()
+	if len(calls) != 1 || !reflect.DeepEqual(calls[0].Args, []string{"10", "15"}) {
+		t.Errorf("script args = %v, want [10 15]", calls[0].Args)
+	}
+}
+
+func TestGetCurrentPlaylistTracksInvalidPage(t *testing.T) {
+	runner := NewFakeScriptRunner()
+	for _, page := range [][2]int{{-1, 10}, {0, 0}} {
+		if _, err := newTestAppleMusicBridge(runner).GetCurrentPlaylistTracks(context.Background(), page[0], page[1]); err == nil {
+			t.Errorf("GetCurrentPlaylistTracks(%d, %d) want an error", page[0], page[1])
+		}
+	}
+	if calls := runner.Calls(); len(calls) != 0 {
+		t.Errorf("an invalid page ran %d scripts", len(calls))
+	}
+}
+
+func TestGetTrackByIdNotFound(t *testing.T) {
+	runner := NewFakeScriptRunner().OnStdout("null\n", "trackJSON(found[0]")
+	_, err := newTestAppleMusicBridge(runner).GetTrackById(context.Background(), "MISSING")
+	if !errors.Is(err, ErrTrackNotFound) {
+		t.Errorf("GetTrackById error = %v, want ErrTrackNotFound", err)
+	}
+}
@@ -0,0 +1,106 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Errors reported by the bridges, check them with errors.Is.
+var (
+	ErrAppNotRunning    = errors.New("music app is not running")
+	ErrPermissionDenied = errors.New("not allowed to control the music app, check Privacy & Security > Automation")
+	ErrTrackNotFound    = errors.New("track not found")
+	ErrPlaylistNotFound = errors.New("playlist not found")
+	ErrTimeout          = errors.New("timed out waiting for the player")
+	ErrNotSupported     = errors.New("not supported by this player")
+	ErrPlaylistChanged  = errors.New("playlist changed since it was listed")
+)
+
+// Error numbers raised by the scripts themselves, see appleScriptHandlers.
+const (
+	scriptErrTrackNotFound    = 1001
+	scriptErrPlaylistNotFound = 1002
+	scriptErrPlaylistChanged  = 1003
+)
+
+// errorNumbers map the error numbers of osascript onto the errors above.
+var errorNumbers = map[int]error{
+	-600:  ErrAppNotRunning,    // procNotFound: application isn't running
+	-609:  ErrAppNotRunning,    // connectionInvalid: the app quit while the script was talking to it
+	-1712: ErrTimeout,          // errAETimeout: AppleEvent timed out
+	-1743: ErrPermissionDenied, // errAEEventNotPermitted: not authorized to send Apple events
+	-1744: ErrPermissionDenied, // errAEEventWouldRequireUserConsent
+
+	scriptErrTrackNotFound:    ErrTrackNotFound,
+	scriptErrPlaylistNotFound: ErrPlaylistNotFound,
+	scriptErrPlaylistChanged:  ErrPlaylistChanged,
+}
+
+// checkReorder return ErrPlaylistChanged unless trackIds hold the tracks of the playlist, in any order.
+// A reorder replace every track by trackIds, a track missing from a stale list would be lost.
+func checkReorder(playlistId string, current, trackIds []string) error {
+	counts := map[string]int{}
+	for _, id := range current {
+		counts[id]++
+	}
+	for _, id := range trackIds {
+		counts[id]--
+		if counts[id] < 0 {
+			return fmt.Errorf("%w: %s", ErrPlaylistChanged, playlistId)
+		}
+	}
+	if len(current) != len(trackIds) {
+		return fmt.Errorf("%w: %s", ErrPlaylistChanged, playlistId)
+	}
+	return nil
+}
+
+// osascript end every error message with its number, like "execution error: ... (-1743)"
+var errorNumberPattern = regexp.MustCompile(`\((-?\d+)\)\s*$`)
+
+// ErrorNumber return the error number osascript printed to stderr, ok is false when there is none.
+func (e *ExitError) ErrorNumber() (int, bool) {
+	match := errorNumberPattern.FindStringSubmatch(e.Stderr)
+	if match == nil {
+		return 0, false
+	}
+	number, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, false
+	}
+	return number, true
+}
+
+// Unwrap return the error the error number stand for, so errors.Is(err, ErrAppNotRunning) work on an ExitError.
+func (e *ExitError) Unwrap() error {
+	number, ok := e.ErrorNumber()
+	if !ok {
+		return nil
+	}
+	return errorNumbers[number]
+}
+
+// ParseError is returned when the output of a script can not be decoded.
+type ParseError struct {
+	Output string
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("error parsing script output %q: %v", truncate(e.Output, 80), e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(strings.TrimSpace(s))
+	if len(runes) <= max {
+		return string(runes)
+	}
+	return string(runes[:max]) + "..."
+}
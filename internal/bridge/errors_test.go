@@ -0,0 +1,115 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestExitErrorUnwrap(t *testing.T) {
+	tests := []struct {
+		stderr string
+		want   error
+	}{
+		{"execution error: Music got an error: Application isn't running. (-600)\n", ErrAppNotRunning},
+		{"execution error: Music got an error: Connection is invalid. (-609)", ErrAppNotRunning},
+		{"execution error: Not authorized to send Apple events to Music. (-1743)\n", ErrPermissionDenied},
+		{"execution error: Music got an error: AppleEvent timed out. (-1712)\n", ErrTimeout},
+		{"execution error: track not found: ABC (1001)\n", ErrTrackNotFound},
+		{"execution error: playlist not found: DEF (1002)\n", ErrPlaylistNotFound},
+		{"execution error: playlist changed: DEF (1003)\n", ErrPlaylistChanged},
+		{"execution error: Can’t get current track. (-1728)\n", nil},
+		{"syntax error: Expected end of line. (-2741)", nil},
+		{"no number here", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		err := &ExitError{ExitCode: 1, Stderr: tt.stderr}
+		if got := err.Unwrap(); got != tt.want {
+			t.Errorf("Unwrap(%q) = %v, want %v", tt.stderr, got, tt.want)
+		}
+	}
+}
+
+func TestCheckReorder(t *testing.T) {
+	current := []string{"T1", "T2", "T2", "T3"}
+	tests := []struct {
+		trackIds []string
+		want     error
+	}{
+		{[]string{"T3", "T2", "T1", "T2"}, nil},
+		{[]string{"T1", "T2", "T3"}, ErrPlaylistChanged},             // a track would be lost
+		{[]string{"T1", "T2", "T2", "T3", "T4"}, ErrPlaylistChanged}, // a track was added since
+		{[]string{"T1", "T1", "T2", "T3"}, ErrPlaylistChanged},       // same length, other tracks
+		{nil, ErrPlaylistChanged},
+	}
+	for _, tt := range tests {
+		if err := checkReorder("P1", current, tt.trackIds); !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+			t.Errorf("checkReorder(%q) = %v, want %v", tt.trackIds, err, tt.want)
+		}
+	}
+	if err := checkReorder("P1", nil, nil); err != nil {
+		t.Errorf("checkReorder of an empty playlist = %v", err)
+	}
+}
+
+// the typed errors reach the caller through the wrapping of the getters and the commands
+func TestTypedErrorsThroughBridge(t *testing.T) {
+	notFound := ScriptResult{ExitCode: 1, Stderr: "execution error: track not found: X (1001)\n"}
+	notRunning := ScriptResult{ExitCode: 1, Stderr: "execution error: Music got an error: Application isn't running. (-600)\n"}
+	denied := ScriptResult{ExitCode: 1, Stderr: "execution error: Not authorized to send Apple events to Music. (-1743)\n"}
+	changed := ScriptResult{ExitCode: 1, Stderr: "execution error: playlist changed: P1 (1003)\n"}
+
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		run  func(a PlayerBridge) error
+		rule ScriptResult
+		want error
+	}{
+		{"play track not found", func(a PlayerBridge) error { return cmdErr(a.PlayTrackById(ctx, "X")) }, notFound, ErrTrackNotFound},
+		{"play pause not running", func(a PlayerBridge) error { return cmdErr(a.PlayPause(ctx)) }, notRunning, ErrAppNotRunning},
+		{"reorder stale list", func(a PlayerBridge) error { return cmdErr(a.ReorderPlaylist(ctx, "P1", []string{"T1"})) }, changed, ErrPlaylistChanged},
+		{"shuffle denied", func(a PlayerBridge) error { _, err := a.GetShuffle(ctx); return err }, denied, ErrPermissionDenied},
+		{"playlists denied", func(a PlayerBridge) error { _, err := a.GetUserPlaylists(ctx); return err }, denied, ErrPermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := NewFakeScriptRunner().On(tt.rule)
+			err := tt.run(newTestAppleMusicBridge(runner))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+			var exitErr *ExitError
+			if !errors.As(err, &exitErr) || exitErr.ExitCode != 1 {
+				t.Errorf("error = %v, want the *ExitError kept in the chain", err)
+			}
+		})
+	}
+}
+
+func TestFakeScriptRunnerDoneContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewFakeScriptRunner().OnStdout("true").Run(ctx, Script{Source: "return true"})
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrTimeout) {
+		t.Errorf("Run on a cancelled context = %v, want context.Canceled", err)
+	}
+
+	// past the deadline the fake answer as osascriptRunner does
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	_, err = NewFakeScriptRunner().OnStdout("true").Run(ctx, Script{Source: "return true"})
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run past the deadline = %v, want ErrTimeout", err)
+	}
+}
+
+// cmdErr run a bridge command and return the error it reported
+func cmdErr(cmd tea.Cmd) error {
+	err, _ := cmd().(error)
+	return err
+}
@@ -0,0 +1,824 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"limiu82214/lazyAppleMusic/internal/artwork"
+	"limiu82214/lazyAppleMusic/internal/constant"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"limiu82214/lazyAppleMusic/internal/util"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// FakeLibrary is the fixture format loaded by the fake bridge.
+//
+//	{
+//	  "tracks":    [{"id": "A1", "name": "Song", "artist": "Someone", "duration": 215.3}],
+//	  "playlists": [{"id": "P1", "name": "Library", "tracks": ["A1"]}]
+//	}
+type FakeLibrary struct {
+	Tracks    []model.Track         `json:"tracks"`
+	Playlists []FakeLibraryPlaylist `json:"playlists"`
+	Volume    int                   `json:"volume"`
+}
+
+type FakeLibraryPlaylist struct {
+	Id        string   `json:"id"`
+	Name      string   `json:"name"`
+	Favorited bool     `json:"favorited"`
+	Tracks    []string `json:"tracks"`
+}
+
+func LoadFakeLibrary(path string) (FakeLibrary, error) {
+	lib := FakeLibrary{Volume: 50}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return lib, fmt.Errorf("error reading fake library: %v", err)
+	}
+	if err := json.Unmarshal(data, &lib); err != nil {
+		return lib, fmt.Errorf("error parsing fake library: %v", err)
+	}
+	return lib, nil
+}
+
+// fakeBridge keep the whole player in memory, the position advance with the wall clock.
+// Every call answer at once, so the contexts are not used.
+type fakeBridge struct {
+	mu   sync.Mutex
+	dump io.Writer
+	now  func() time.Time
+
+	tracks    map[string]model.Track
+	playlists []FakeLibraryPlaylist
+
+	playlistIdx int
+	trackIdx    int
+	playing     bool
+	position    time.Duration // position at positionAt
+	positionAt  time.Time
+	volume      int
+	shuffle     bool
+	repeat      model.RepeatMode
+
+	artworks *artwork.Store
+}
+
+func NewFakeBridge(dump io.Writer, lib FakeLibrary, artworks *artwork.Store) PlayerBridge {
+	f := &fakeBridge{
+		dump:      dump,
+		now:       time.Now,
+		tracks:    map[string]model.Track{},
+		playlists: lib.Playlists,
+		volume:    lib.Volume,
+		repeat:    model.RepeatAll,
+		artworks:  artworks,
+	}
+	for _, t := range lib.Tracks {
+		if t.Time == "" {
+			t.Time = formatTrackTime(t.Duration)
+		}
+		f.tracks[t.Id] = t
+	}
+	f.positionAt = f.now()
+	f.playing = len(f.currentPlaylistTrackIds()) > 0
+	return f
+}
+
+func (f *fakeBridge) log(msg interface{}) {
+	if f.dump != nil {
+		spew.Fdump(f.dump, msg)
+	}
+}
+
+func formatTrackTime(duration float64) string {
+	sec := int(duration)
+	return fmt.Sprintf("%d:%02d", sec/60, sec%60)
+}
+
+// ======= state, caller should hold mu
+
+func (f *fakeBridge) currentPlaylistTrackIds() []string {
+	if f.playlistIdx < 0 || f.playlistIdx >= len(f.playlists) {
+		return nil
+	}
+	return f.playlists[f.playlistIdx].Tracks
+}
+
+func (f *fakeBridge) currentTrack() (model.Track, bool) {
+	ids := f.currentPlaylistTrackIds()
+	if f.trackIdx < 0 || f.trackIdx >= len(ids) {
+		return model.Track{}, false
+	}
+	t, ok := f.tracks[ids[f.trackIdx]]
+	return t, ok
+}
+
+// advance move the position forward to now, switching to the next track when one ends.
+func (f *fakeBridge) advance() {
+	now := f.now()
+	if f.playing {
+		f.position += now.Sub(f.positionAt)
+	}
+	f.positionAt = now
+
+	for {
+		t, ok := f.currentTrack()
+		duration := time.Duration(t.Duration * float64(time.Second))
+		if !ok || duration <= 0 || f.position < duration {
+			return
+		}
+		f.position -= duration
+		if f.repeat == model.RepeatOne {
+			continue
+		}
+		next, ok := f.nextTrackIdx()
+		if !ok {
+			// end of the playlist
+			f.position = 0
+			f.playing = false
+			return
+		}
+		f.trackIdx = next
+	}
+}
+
+// nextTrackIdx pick the track played after the current one, ok is false when the playlist end.
+func (f *fakeBridge) nextTrackIdx() (int, bool) {
+	ids := f.currentPlaylistTrackIds()
+	if len(ids) == 0 {
+		return 0, false
+	}
+	if f.shuffle && len(ids) > 1 {
+		next := rand.IntN(len(ids) - 1)
+		if next >= f.trackIdx {
+			next++
+		}
+		return next, true
+	}
+	if f.trackIdx+1 < len(ids) {
+		return f.trackIdx + 1, true
+	}
+	return 0, f.repeat == model.RepeatAll
+}
+
+func (f *fakeBridge) jumpTo(playlistIdx, trackIdx int) {
+	f.playlistIdx = playlistIdx
+	f.trackIdx = trackIdx
+	f.position = 0
+	f.positionAt = f.now()
+	f.playing = true
+}
+
+func (f *fakeBridge) toggleFavorite(id string) bool {
+	t, ok := f.tracks[id]
+	if !ok {
+		return false
+	}
+	t.Favorited = !t.Favorited
+	if t.Favorited {
+		// like Music.app, a track is either favorited or disliked
+		t.Disliked = false
+	}
+	f.tracks[id] = t
+	return true
+}
+
+// ======= PlayerBridge
+
+// LaunchApp do nothing, the fake player is always running
+func (f *fakeBridge) LaunchApp(ctx context.Context) tea.Cmd {
+	return util.ToTeaCmdMsg(constant.EventPlayerStateChanged{})
+}
+
+func (f *fakeBridge) PlayPause(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.advance()
+		f.playing = !f.playing
+		return constant.EventPlayerStateChanged{}
+	}
+}
+
+func (f *fakeBridge) Play(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.advance()
+		f.playing = true
+		return constant.EventPlayerStateChanged{}
+	}
+}
+
+func (f *fakeBridge) Pause(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.advance()
+		f.playing = false
+		return constant.EventPlayerStateChanged{}
+	}
+}
+
+func (f *fakeBridge) NextTrack(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.advance()
+		ids := f.currentPlaylistTrackIds()
+		if len(ids) == 0 {
+			return nil
+		}
+		next, ok := f.nextTrackIdx()
+		if !ok {
+			// skipping the last track wrap around, like Music.app does
+			next = 0
+		}
+		f.jumpTo(f.playlistIdx, next)
+		return constant.EventTrackChanged{}
+	}
+}
+
+func (f *fakeBridge) PreviousTrack(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.advance()
+		ids := f.currentPlaylistTrackIds()
+		if len(ids) == 0 {
+			return nil
+		}
+		// like Music.app, go back to the start of the track first
+		if f.position > 3*time.Second {
+			f.jumpTo(f.playlistIdx, f.trackIdx)
+		} else {
+			f.jumpTo(f.playlistIdx, (f.trackIdx-1+len(ids))%len(ids))
+		}
+		return constant.EventTrackChanged{}
+	}
+}
+
+func (f *fakeBridge) SetVolume(ctx context.Context, volume int) tea.Cmd {
+	return func() tea.Msg {
+		if volume < 0 || volume > 100 {
+			return fmt.Errorf("volume must be between 0 and 100")
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.volume = volume
+		return nil
+	}
+}
+
+func (f *fakeBridge) IncreaseVolume(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.volume = min(f.volume+10, 100)
+		return nil
+	}
+}
+
+func (f *fakeBridge) DecreaseVolume(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.volume = max(f.volume-10, 0)
+		return nil
+	}
+}
+
+func (f *fakeBridge) PlayPlaylist(ctx context.Context, playlistName string) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for i, p := range f.playlists {
+			if p.Name == playlistName {
+				f.jumpTo(i, 0)
+				return constant.EventTrackChanged{}
+			}
+		}
+		err := fmt.Errorf("%w: %s", ErrPlaylistNotFound, playlistName)
+		f.log(err.Error())
+		return err
+	}
+}
+
+func (f *fakeBridge) PlayTrackById(ctx context.Context, id string) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		// the fake has no library playlist, the first playlist containing the track stand for it
+		for i, p := range f.playlists {
+			for j, trackId := range p.Tracks {
+				if trackId == id {
+					f.jumpTo(i, j)
+					return constant.EventTrackChanged{}
+				}
+			}
+		}
+		err := fmt.Errorf("%w: %s", ErrTrackNotFound, id)
+		f.log(err.Error())
+		return err
+	}
+}
+
+func (f *fakeBridge) PlayTrackInPlaylist(ctx context.Context, playlistId, trackId string) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for i, p := range f.playlists {
+			if p.Id != playlistId {
+				continue
+			}
+			for j, id := range p.Tracks {
+				if id == trackId {
+					f.jumpTo(i, j)
+					return constant.EventTrackChanged{}
+				}
+			}
+			err := fmt.Errorf("%w in playlist %s: %s", ErrTrackNotFound, playlistId, trackId)
+			f.log(err.Error())
+			return err
+		}
+		err := fmt.Errorf("%w: %s", ErrPlaylistNotFound, playlistId)
+		f.log(err.Error())
+		return err
+	}
+}
+
+func (f *fakeBridge) FavoriteCurrentTrack(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.advance()
+		t, ok := f.currentTrack()
+		if !ok {
+			return nil
+		}
+		f.toggleFavorite(t.Id)
+		return constant.EventFavoriteTrackId(t.Id)
+	}
+}
+
+func (f *fakeBridge) FavoriteTrackByTrackId(ctx context.Context, id string) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if !f.toggleFavorite(id) {
+			err := fmt.Errorf("%w: %s", ErrTrackNotFound, id)
+			f.log(err.Error())
+			return err
+		}
+		return constant.EventFavoriteTrackId(id)
+	}
+}
+
+func (f *fakeBridge) SetRating(ctx context.Context, trackId string, rating int) tea.Cmd {
+	return func() tea.Msg {
+		if rating < 0 || rating > 100 {
+			return fmt.Errorf("invalid rating: %d", rating)
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		t, ok := f.tracks[trackId]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrTrackNotFound, trackId)
+		}
+		t.Rating = rating
+		f.tracks[trackId] = t
+		return constant.EventTrackRatingChanged{TrackId: trackId, Rating: rating}
+	}
+}
+
+func (f *fakeBridge) SetDisliked(ctx context.Context, trackId string, disliked bool) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		t, ok := f.tracks[trackId]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrTrackNotFound, trackId)
+		}
+		t.Disliked = disliked
+		if disliked {
+			t.Favorited = false
+		}
+		f.tracks[trackId] = t
+		return constant.EventTrackDislikedChanged{TrackId: trackId, Disliked: disliked}
+	}
+}
+
+func (f *fakeBridge) SetShuffle(ctx context.Context, enabled bool) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.shuffle = enabled
+		return constant.EventUpdateShuffle(enabled)
+	}
+}
+
+func (f *fakeBridge) SetRepeat(ctx context.Context, mode model.RepeatMode) tea.Cmd {
+	return func() tea.Msg {
+		if !mode.Valid() {
+			return fmt.Errorf("unknown repeat mode: %s", mode)
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.repeat = mode
+		return constant.EventUpdateRepeat(mode)
+	}
+}
+
+func (f *fakeBridge) SetPlayerPosition(ctx context.Context, seconds int) tea.Cmd {
+	return func() tea.Msg {
+		if seconds < 0 {
+			return fmt.Errorf("position must not be negative")
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return f.seekTo(time.Duration(seconds) * time.Second)
+	}
+}
+
+func (f *fakeBridge) Seek(ctx context.Context, deltaSeconds int) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.advance()
+		return f.seekTo(max(f.position+time.Duration(deltaSeconds)*time.Second, 0))
+	}
+}
+
+// seekTo set the position, seeking past the end play the next track like Music.app
+func (f *fakeBridge) seekTo(position time.Duration) tea.Msg {
+	trackId := ""
+	if t, ok := f.currentTrack(); ok {
+		trackId = t.Id
+	}
+	f.position = position
+	f.positionAt = f.now()
+	f.advance()
+	if t, ok := f.currentTrack(); ok && t.Id != trackId {
+		return constant.EventTrackChanged{}
+	}
+	return constant.EventPlayerPositionChanged(int(f.position.Seconds()))
+}
+
+func (f *fakeBridge) GetNowPlaying(ctx context.Context) (model.NowPlaying, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.advance()
+
+	nowPlaying := model.NowPlaying{
+		Track:    model.Track{Name: "No Track Playing"},
+		Position: f.position.Seconds(),
+		Volume:   f.volume,
+		Shuffle:  f.shuffle,
+		Repeat:   f.repeat,
+		State:    f.state(),
+	}
+	if t, ok := f.currentTrack(); ok {
+		nowPlaying.Track = t
+	}
+	if f.playlistIdx >= 0 && f.playlistIdx < len(f.playlists) {
+		p := f.playlists[f.playlistIdx]
+		nowPlaying.Playlist = model.Playlist{
+			Id:         p.Id,
+			Name:       p.Name,
+			Favorited:  p.Favorited,
+			TrackCount: len(p.Tracks),
+			Stamp:      strings.Join(p.Tracks, ","),
+		}
+	}
+	return nowPlaying, nil
+}
+
+func (f *fakeBridge) GetPlayerState(ctx context.Context) (model.PlayerState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.advance()
+	return f.state(), nil
+}
+
+func (f *fakeBridge) state() model.PlayerState {
+	if _, ok := f.currentTrack(); !ok {
+		return model.PlayerStopped
+	}
+	if f.playing {
+		return model.PlayerPlaying
+	}
+	return model.PlayerPaused
+}
+
+func (f *fakeBridge) GetShuffle(ctx context.Context) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.shuffle, nil
+}
+
+func (f *fakeBridge) GetRepeat(ctx context.Context) (model.RepeatMode, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.repeat, nil
+}
+
+func (f *fakeBridge) GetPlayerPosition(ctx context.Context) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.advance()
+	return int(f.position.Seconds()), nil
+}
+
+// GetArtwork store a gradient generated from the album name, good enough to see the layout.
+func (f *fakeBridge) GetArtwork(ctx context.Context, track model.Track) (string, error) {
+	key := artwork.Key(track)
+	if key == "" {
+		return "", nil
+	}
+	if path, ok := f.artworks.Lookup(key); ok {
+		return path, nil
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(track.Album + track.AlbumArtist))
+	seed := h.Sum32()
+	from := color.RGBA{uint8(seed), uint8(seed >> 8), uint8(seed >> 16), 255}
+	to := color.RGBA{255 - from.R, 255 - from.G, 255 - from.B, 255}
+
+	const size = 64
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			ratio := float64(x+y) / float64(2*size)
+			img.Set(x, y, color.RGBA{
+				uint8(float64(from.R)*(1-ratio) + float64(to.R)*ratio),
+				uint8(float64(from.G)*(1-ratio) + float64(to.G)*ratio),
+				uint8(float64(from.B)*(1-ratio) + float64(to.B)*ratio),
+				255,
+			})
+		}
+	}
+
+	path, err := f.artworks.Put(key, func(path string) error {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return png.Encode(file, img)
+	})
+	if err != nil {
+		return "", fmt.Errorf("error getting artwork: %w", err)
+	}
+	return path, nil
+}
+
+func (f *fakeBridge) GetCurrentTrack(ctx context.Context) (model.Track, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.advance()
+	t, ok := f.currentTrack()
+	if !ok {
+		return model.Track{Name: "No Track Playing"}, nil
+	}
+	return t, nil
+}
+
+func (f *fakeBridge) GetPlaylists(ctx context.Context) ([]model.Playlist, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	playlists := make([]model.Playlist, 0, len(f.playlists))
+	for _, p := range f.playlists {
+		playlists = append(playlists, model.Playlist{Id: p.Id, Name: p.Name, Favorited: p.Favorited})
+	}
+	return playlists, nil
+}
+
+// GetUserPlaylists return every playlist, the fake has no smart playlists.
+func (f *fakeBridge) GetUserPlaylists(ctx context.Context) ([]model.Playlist, error) {
+	return f.GetPlaylists(ctx)
+}
+
+func (f *fakeBridge) GetCurrentPlaylistInfo(ctx context.Context) (model.Playlist, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.playlistIdx < 0 || f.playlistIdx >= len(f.playlists) {
+		return model.Playlist{}, nil
+	}
+	p := f.playlists[f.playlistIdx]
+	return model.Playlist{Id: p.Id, Name: p.Name, Favorited: p.Favorited, TrackCount: len(p.Tracks)}, nil
+}
+
+func (f *fakeBridge) GetCurrentPlaylistTracks(ctx context.Context, offset, limit int) ([]model.Track, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, fmt.Errorf("invalid page: offset %d, limit %d", offset, limit)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ids := f.currentPlaylistTrackIds()
+	tracks := []model.Track{}
+	for i := offset; i < len(ids) && i < offset+limit; i++ {
+		t := f.tracks[ids[i]]
+		tracks = append(tracks, model.Track{
+			Id:        t.Id,
+			Name:      t.Name,
+			Artist:    t.Artist,
+			Favorited: t.Favorited,
+			Rating:    t.Rating,
+			Disliked:  t.Disliked,
+		})
+	}
+	return tracks, nil
+}
+
+func (f *fakeBridge) GetTrackById(ctx context.Context, id string) (model.Track, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t, ok := f.tracks[id]
+	if !ok {
+		return model.Track{}, fmt.Errorf("%w: %s", ErrTrackNotFound, id)
+	}
+	return t, nil
+}
+
+// SearchLibrary match query as a case insensitive substring, like Music.app does.
+func (f *fakeBridge) SearchLibrary(ctx context.Context, query string, field model.SearchField) ([]model.Track, error) {
+	if !field.Valid() {
+		return nil, fmt.Errorf("unknown search field: %s", field)
+	}
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return []model.Track{}, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tracks := []model.Track{}
+	for _, t := range f.tracks {
+		values := []string{}
+		switch field {
+		case model.SearchSongs:
+			values = append(values, t.Name)
+		case model.SearchArtists:
+			values = append(values, t.Artist, t.AlbumArtist)
+		case model.SearchAlbums:
+			values = append(values, t.Album)
+		default:
+			values = append(values, t.Name, t.Artist, t.AlbumArtist, t.Album)
+		}
+		for _, value := range values {
+			if strings.Contains(strings.ToLower(value), query) {
+				tracks = append(tracks, t)
+				break
+			}
+		}
+	}
+	// the map has no order, keep the results stable
+	sort.Slice(tracks, func(i, j int) bool { return tracks[i].Id < tracks[j].Id })
+	if len(tracks) > searchLimit {
+		tracks = tracks[:searchLimit]
+	}
+	return tracks, nil
+}
+
+// ======= playlist management
+
+func (f *fakeBridge) playlistIndex(id string) int {
+	for i, p := range f.playlists {
+		if p.Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// setPlaylistTracks replace the tracks of the playlist, the current track stay the same when it is still there.
+func (f *fakeBridge) setPlaylistTracks(idx int, tracks []string) {
+	if idx == f.playlistIdx {
+		current := ""
+		if ids := f.currentPlaylistTrackIds(); f.trackIdx >= 0 && f.trackIdx < len(ids) {
+			current = ids[f.trackIdx]
+		}
+		for i, id := range tracks {
+			if id == current {
+				f.trackIdx = i
+				break
+			}
+		}
+		f.trackIdx = min(f.trackIdx, len(tracks)-1)
+	}
+	f.playlists[idx].Tracks = tracks
+}
+
+func (f *fakeBridge) CreatePlaylist(ctx context.Context, name string) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.playlists = append(f.playlists, FakeLibraryPlaylist{
+			Id:   fmt.Sprintf("P%015X", f.now().UnixNano()),
+			Name: name,
+		})
+		p := f.playlists[len(f.playlists)-1]
+		return constant.EventPlaylistCreated(model.Playlist{Id: p.Id, Name: p.Name})
+	}
+}
+
+func (f *fakeBridge) RenamePlaylist(ctx context.Context, playlistId, name string) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		idx := f.playlistIndex(playlistId)
+		if idx < 0 {
+			return fmt.Errorf("%w: %s", ErrPlaylistNotFound, playlistId)
+		}
+		f.playlists[idx].Name = name
+		return constant.EventPlaylistsChanged{}
+	}
+}
+
+func (f *fakeBridge) DeletePlaylist(ctx context.Context, playlistId string) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		idx := f.playlistIndex(playlistId)
+		if idx < 0 {
+			return fmt.Errorf("%w: %s", ErrPlaylistNotFound, playlistId)
+		}
+		f.playlists = append(f.playlists[:idx:idx], f.playlists[idx+1:]...)
+		switch {
+		case idx == f.playlistIdx:
+			// like Music.app, the player stop without playlist
+			f.playlistIdx, f.trackIdx = -1, -1
+			f.playing = false
+		case idx < f.playlistIdx:
+			f.playlistIdx--
+		}
+		return constant.EventPlaylistsChanged{}
+	}
+}
+
+func (f *fakeBridge) AddTracksToPlaylist(ctx context.Context, playlistId string, trackIds []string) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		idx := f.playlistIndex(playlistId)
+		if idx < 0 {
+			return fmt.Errorf("%w: %s", ErrPlaylistNotFound, playlistId)
+		}
+		for _, id := range trackIds {
+			if _, ok := f.tracks[id]; !ok {
+				return fmt.Errorf("%w: %s", ErrTrackNotFound, id)
+			}
+		}
+		tracks := append(slices.Clone(f.playlists[idx].Tracks), trackIds...)
+		f.setPlaylistTracks(idx, tracks)
+		return constant.EventPlaylistChanged(playlistId)
+	}
+}
+
+func (f *fakeBridge) RemoveTracksFromPlaylist(ctx context.Context, playlistId string, trackIds []string) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		idx := f.playlistIndex(playlistId)
+		if idx < 0 {
+			return fmt.Errorf("%w: %s", ErrPlaylistNotFound, playlistId)
+		}
+		tracks := slices.DeleteFunc(slices.Clone(f.playlists[idx].Tracks), func(id string) bool {
+			return slices.Contains(trackIds, id)
+		})
+		f.setPlaylistTracks(idx, tracks)
+		return constant.EventPlaylistChanged(playlistId)
+	}
+}
+
+func (f *fakeBridge) ReorderPlaylist(ctx context.Context, playlistId string, trackIds []string) tea.Cmd {
+	return func() tea.Msg {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		idx := f.playlistIndex(playlistId)
+		if idx < 0 {
+			return fmt.Errorf("%w: %s", ErrPlaylistNotFound, playlistId)
+		}
+		if err := checkReorder(playlistId, f.playlists[idx].Tracks, trackIds); err != nil {
+			return err
+		}
+		f.setPlaylistTracks(idx, slices.Clone(trackIds))
+		return constant.EventPlaylistChanged(playlistId)
+	}
+}
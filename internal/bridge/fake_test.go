@@ -0,0 +1,55 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"io"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"reflect"
+	"testing"
+)
+
+func TestFakeReorderPlaylist(t *testing.T) {
+	lib := FakeLibrary{
+		Tracks:    []model.Track{{Id: "T1"}, {Id: "T2"}, {Id: "T3"}},
+		Playlists: []FakeLibraryPlaylist{{Id: "P1", Name: "Mix", Tracks: []string{"T1", "T2", "T3"}}},
+	}
+	a := NewFakeBridge(io.Discard, lib, nil)
+	ctx := context.Background()
+
+	// a stale list leave the playlist as it is
+	if err := cmdErr(a.ReorderPlaylist(ctx, "P1", []string{"T2", "T1"})); !errors.Is(err, ErrPlaylistChanged) {
+		t.Errorf("ReorderPlaylist with a track missing = %v, want ErrPlaylistChanged", err)
+	}
+	if err := cmdErr(a.ReorderPlaylist(ctx, "P9", nil)); !errors.Is(err, ErrPlaylistNotFound) {
+		t.Errorf("ReorderPlaylist of an unknown playlist = %v, want ErrPlaylistNotFound", err)
+	}
+	if got := fakePlaylistTrackIds(t, a, "Mix"); !reflect.DeepEqual(got, []string{"T1", "T2", "T3"}) {
+		t.Errorf("tracks after failed reorders = %q", got)
+	}
+
+	if err := cmdErr(a.ReorderPlaylist(ctx, "P1", []string{"T3", "T1", "T2"})); err != nil {
+		t.Fatalf("ReorderPlaylist: %v", err)
+	}
+	if got := fakePlaylistTrackIds(t, a, "Mix"); !reflect.DeepEqual(got, []string{"T3", "T1", "T2"}) {
+		t.Errorf("tracks after reorder = %q", got)
+	}
+}
+
+// fakePlaylistTrackIds play the playlist and list its tracks
+func fakePlaylistTrackIds(t *testing.T, a PlayerBridge, name string) []string {
+	t.Helper()
+	ctx := context.Background()
+	if err := cmdErr(a.PlayPlaylist(ctx, name)); err != nil {
+		t.Fatalf("PlayPlaylist: %v", err)
+	}
+	tracks, err := a.GetCurrentPlaylistTracks(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("GetCurrentPlaylistTracks: %v", err)
+	}
+	ids := []string{}
+	for _, track := range tracks {
+		ids = append(ids, track.Id)
+	}
+	return ids
+}
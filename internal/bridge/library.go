@@ -0,0 +1,138 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"limiu82214/lazyAppleMusic/internal/constant"
+	"limiu82214/lazyAppleMusic/internal/library"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"sync/atomic"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// libraryBridge read the tracks and playlists from an exported library, and play through the live bridge.
+// While the player is not running the whole library is offered as the current playlist, so it can still be browsed.
+type libraryBridge struct {
+	PlayerBridge
+	lib *library.Library
+	// offline is set by the last GetNowPlaying, the other calls of the live bridge would launch the player
+	offline atomic.Bool
+}
+
+// NewLibraryBridge wrap live, the listing and searching of tracks are answered by lib.
+func NewLibraryBridge(live PlayerBridge, lib *library.Library) PlayerBridge {
+	return &libraryBridge{
+		PlayerBridge: live,
+		lib:          lib,
+	}
+}
+
+// remember apply the changes reported by cmd to the library, so later listings agree with the player
+func (a *libraryBridge) remember(cmd tea.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		msg := cmd()
+		switch msg := msg.(type) {
+		case constant.EventFavoriteTrackId:
+			a.lib.Update(string(msg), func(t *model.Track) { t.Favorited = !t.Favorited })
+		case constant.EventTrackRatingChanged:
+			a.lib.Update(msg.TrackId, func(t *model.Track) { t.Rating = msg.Rating })
+		case constant.EventTrackDislikedChanged:
+			a.lib.Update(msg.TrackId, func(t *model.Track) {
+				t.Disliked = msg.Disliked
+				if msg.Disliked {
+					t.Favorited = false
+				}
+			})
+		}
+		return msg
+	}
+}
+
+func (a *libraryBridge) FavoriteCurrentTrack(ctx context.Context) tea.Cmd {
+	return a.remember(a.PlayerBridge.FavoriteCurrentTrack(ctx))
+}
+
+func (a *libraryBridge) FavoriteTrackByTrackId(ctx context.Context, id string) tea.Cmd {
+	return a.remember(a.PlayerBridge.FavoriteTrackByTrackId(ctx, id))
+}
+
+func (a *libraryBridge) SetRating(ctx context.Context, trackId string, rating int) tea.Cmd {
+	return a.remember(a.PlayerBridge.SetRating(ctx, trackId, rating))
+}
+
+func (a *libraryBridge) SetDisliked(ctx context.Context, trackId string, disliked bool) tea.Cmd {
+	return a.remember(a.PlayerBridge.SetDisliked(ctx, trackId, disliked))
+}
+
+// GetNowPlaying offer the whole library as the current playlist while the player is not running.
+func (a *libraryBridge) GetNowPlaying(ctx context.Context) (model.NowPlaying, error) {
+	nowPlaying, err := a.PlayerBridge.GetNowPlaying(ctx)
+	if err != nil && !errors.Is(err, ErrAppNotRunning) {
+		// a timeout or a denied permission tell nothing about whether the player run
+		return nowPlaying, err
+	}
+	offline := err != nil || nowPlaying.State == model.PlayerNotRunning
+	a.offline.Store(offline)
+	if offline {
+		nowPlaying.State = model.PlayerNotRunning
+		nowPlaying.Playlist = a.lib.Root()
+		return nowPlaying, nil
+	}
+	return nowPlaying, nil
+}
+
+// GetCurrentPlaylistInfo return the library while the player is not running.
+func (a *libraryBridge) GetCurrentPlaylistInfo(ctx context.Context) (model.Playlist, error) {
+	if a.offline.Load() {
+		return a.lib.Root(), nil
+	}
+	playlist, err := a.PlayerBridge.GetCurrentPlaylistInfo(ctx)
+	if errors.Is(err, ErrAppNotRunning) {
+		return a.lib.Root(), nil
+	}
+	return playlist, err
+}
+
+// GetCurrentPlaylistTracks page the current playlist from the library when the export know it.
+// A playlist changed since the export is listed by the live bridge.
+func (a *libraryBridge) GetCurrentPlaylistTracks(ctx context.Context, offset, limit int) ([]model.Track, error) {
+	playlist, err := a.GetCurrentPlaylistInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting current playlist tracks: %w", err)
+	}
+	if exported, ok := a.lib.Playlist(playlist.Id); ok && exported.TrackCount == playlist.TrackCount {
+		tracks, _ := a.lib.PlaylistTracks(playlist.Id, offset, limit)
+		return tracks, nil
+	}
+	return a.PlayerBridge.GetCurrentPlaylistTracks(ctx, offset, limit)
+}
+
+// GetPlaylists return the playlists and folders of the export.
+func (a *libraryBridge) GetPlaylists(ctx context.Context) ([]model.Playlist, error) {
+	return a.lib.Playlists(), nil
+}
+
+// GetTrackById ask the live bridge, which know every property of the track, and fall back on the export
+// while the player is not running.
+func (a *libraryBridge) GetTrackById(ctx context.Context, id string) (model.Track, error) {
+	if track, ok := a.lib.Track(id); ok && a.offline.Load() {
+		return track, nil
+	}
+	track, err := a.PlayerBridge.GetTrackById(ctx, id)
+	if errors.Is(err, ErrAppNotRunning) {
+		if track, ok := a.lib.Track(id); ok {
+			return track, nil
+		}
+	}
+	return track, err
+}
+
+// SearchLibrary search the export, at most searchLimit tracks are returned.
+func (a *libraryBridge) SearchLibrary(ctx context.Context, query string, field model.SearchField) ([]model.Track, error) {
+	if !field.Valid() {
+		return nil, fmt.Errorf("unknown search field: %s", field)
+	}
+	return a.lib.Search(query, field, searchLimit), nil
+}
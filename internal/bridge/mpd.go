@@ -0,0 +1,846 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"limiu82214/lazyAppleMusic/internal/artwork"
+	"limiu82214/lazyAppleMusic/internal/constant"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"limiu82214/lazyAppleMusic/internal/util"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// mpdQueueId is the id of the queue of mpd, which is the current playlist
+const mpdQueueId = "queue"
+
+// mpdFavoriteSticker is the sticker marking the favorite songs
+const mpdFavoriteSticker = "favorite"
+
+// mpdRetryDelay is the pause before watching again once the connection was lost
+const mpdRetryDelay = 2 * time.Second
+
+// mpdBridge drive mpd over its text protocol. Tracks are identified by their song uri, so they
+// can be played, favorited (with a sticker) and added to stored playlists wherever they were found.
+// Stored playlists are identified by their name.
+type mpdBridge struct {
+	dump     io.Writer
+	addr     string
+	password string
+	artworks *artwork.Store
+}
+
+// NewMpdBridge create a bridge driving mpd at addr, a host:port or the path of a unix socket.
+func NewMpdBridge(dump io.Writer, addr, password string, artworks *artwork.Store) PlayerBridge {
+	return &mpdBridge{
+		dump:     dump,
+		addr:     addr,
+		password: password,
+		artworks: artworks,
+	}
+}
+
+func (a *mpdBridge) log(msg interface{}) {
+	if a.dump != nil {
+		spew.Fdump(a.dump, msg)
+	}
+}
+
+// do run f on a connection within timeout.
+// mpd drop idle clients, a connection per call is simpler than keeping one alive.
+func (a *mpdBridge) do(ctx context.Context, timeout time.Duration, f func(c *mpdClient) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	c, err := dialMpd(ctx, a.addr, a.password)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return f(c)
+}
+
+// command run a single command
+func (a *mpdBridge) command(ctx context.Context, timeout time.Duration, name string, args ...string) ([]mpdPair, error) {
+	var pairs []mpdPair
+	err := a.do(ctx, timeout, func(c *mpdClient) error {
+		var err error
+		pairs, err = c.command(name, args...)
+		return err
+	})
+	return pairs, err
+}
+
+// commandCmd run the commands as a command list and report msg once done
+func (a *mpdBridge) commandCmd(ctx context.Context, what string, msg tea.Msg, commands ...[]string) tea.Cmd {
+	return func() tea.Msg {
+		err := a.do(ctx, controlTimeout, func(c *mpdClient) error {
+			return c.commandList(commands)
+		})
+		if err != nil {
+			a.log(fmt.Sprintf("Error %s: %v", what, err.Error()))
+			return err
+		}
+		return msg
+	}
+}
+
+// ======= parsing
+
+// mpdSongs split a response listing songs, every song start with its "file" pair
+func mpdSongs(pairs []mpdPair) []model.Track {
+	tracks := []model.Track{}
+	for _, p := range pairs {
+		if p.key == "file" {
+			tracks = append(tracks, model.Track{Id: p.value})
+		}
+		if len(tracks) == 0 {
+			continue
+		}
+		track := &tracks[len(tracks)-1]
+		switch p.key {
+		case "Title":
+			track.Name = p.value
+		case "Artist":
+			if track.Artist != "" {
+				track.Artist += ", "
+			}
+			track.Artist += p.value
+		case "Album":
+			track.Album = p.value
+		case "AlbumArtist":
+			if track.AlbumArtist != "" {
+				track.AlbumArtist += ", "
+			}
+			track.AlbumArtist += p.value
+		case "duration":
+			track.Duration, _ = strconv.ParseFloat(p.value, 64)
+		case "Time":
+			// rounded, older servers only send this one
+			if track.Duration == 0 {
+				track.Duration, _ = strconv.ParseFloat(p.value, 64)
+			}
+		}
+	}
+	for i := range tracks {
+		if tracks[i].Name == "" {
+			tracks[i].Name = path.Base(tracks[i].Id)
+		}
+		tracks[i].Time = formatTrackTime(tracks[i].Duration)
+	}
+	return tracks
+}
+
+// mpdStatus is the part of the status response the bridge use
+type mpdStatus struct {
+	state          model.PlayerState
+	volume         int
+	shuffle        bool
+	repeat         model.RepeatMode
+	elapsed        float64
+	playlistLength int
+	playlist       string // version of the queue, change with its songs
+}
+
+func parseMpdStatus(pairs []mpdPair) mpdStatus {
+	status := mpdStatus{state: model.PlayerStopped, repeat: model.RepeatOff}
+	repeat, single := false, false
+	for _, p := range pairs {
+		switch p.key {
+		case "state":
+			switch p.value {
+			case "play":
+				status.state = model.PlayerPlaying
+			case "pause":
+				status.state = model.PlayerPaused
+			}
+		case "volume":
+			// -1 when mpd has no mixer
+			status.volume, _ = strconv.Atoi(p.value)
+			status.volume = max(status.volume, 0)
+		case "random":
+			status.shuffle = p.value == "1"
+		case "repeat":
+			repeat = p.value == "1"
+		case "single":
+			single = p.value == "1"
+		case "elapsed":
+			status.elapsed, _ = strconv.ParseFloat(p.value, 64)
+		case "playlistlength":
+			status.playlistLength, _ = strconv.Atoi(p.value)
+		case "playlist":
+			status.playlist = p.value
+		}
+	}
+	switch {
+	case repeat && single:
+		status.repeat = model.RepeatOne
+	case repeat:
+		status.repeat = model.RepeatAll
+	}
+	return status
+}
+
+func (a *mpdBridge) status(ctx context.Context, timeout time.Duration) (mpdStatus, error) {
+	pairs, err := a.command(ctx, timeout, "status")
+	if err != nil {
+		return mpdStatus{}, err
+	}
+	return parseMpdStatus(pairs), nil
+}
+
+// favorites return the uris of the favorite songs, none when mpd has no sticker database
+func (a *mpdBridge) favorites(c *mpdClient) map[string]bool {
+	favorites := map[string]bool{}
+	pairs, err := c.command("sticker", "find", "song", "", mpdFavoriteSticker)
+	if err != nil {
+		a.log(fmt.Sprintf("Error getting favorites: %v", err.Error()))
+		return favorites
+	}
+	file := ""
+	for _, p := range pairs {
+		switch p.key {
+		case "file":
+			file = p.value
+		case "sticker":
+			favorites[file] = p.value == mpdFavoriteSticker+"=1"
+		}
+	}
+	return favorites
+}
+
+// songs run a command listing songs and mark the favorite ones
+func (a *mpdBridge) songs(ctx context.Context, timeout time.Duration, name string, args ...string) ([]model.Track, error) {
+	var tracks []model.Track
+	err := a.do(ctx, timeout, func(c *mpdClient) error {
+		pairs, err := c.command(name, args...)
+		if err != nil {
+			return err
+		}
+		tracks = mpdSongs(pairs)
+		favorites := a.favorites(c)
+		for i := range tracks {
+			tracks[i].Favorited = favorites[tracks[i].Id]
+		}
+		return nil
+	})
+	return tracks, err
+}
+
+// ======= playback
+
+// LaunchApp fail, mpd is a daemon the bridge can not start.
+func (a *mpdBridge) LaunchApp(ctx context.Context) tea.Cmd {
+	return util.ToTeaCmdMsg[error](fmt.Errorf("%w: start mpd, then press r", ErrNotSupported))
+}
+
+func (a *mpdBridge) PlayPause(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		err := a.do(ctx, controlTimeout, func(c *mpdClient) error {
+			pairs, err := c.command("status")
+			if err != nil {
+				return err
+			}
+			// pause does nothing once stopped
+			if parseMpdStatus(pairs).state == model.PlayerStopped {
+				_, err = c.command("play")
+			} else {
+				_, err = c.command("pause")
+			}
+			return err
+		})
+		if err != nil {
+			a.log(fmt.Sprintf("Error toggling play/pause: %v", err.Error()))
+			return err
+		}
+		return constant.EventPlayerStateChanged{}
+	}
+}
+
+func (a *mpdBridge) Play(ctx context.Context) tea.Cmd {
+	return a.commandCmd(ctx, "playing track", constant.EventPlayerStateChanged{}, []string{"play"})
+}
+
+func (a *mpdBridge) Pause(ctx context.Context) tea.Cmd {
+	return a.commandCmd(ctx, "pausing track", constant.EventPlayerStateChanged{}, []string{"pause", "1"})
+}
+
+func (a *mpdBridge) NextTrack(ctx context.Context) tea.Cmd {
+	return a.commandCmd(ctx, "skipping to next track", constant.EventTrackChanged{}, []string{"next"})
+}
+
+func (a *mpdBridge) PreviousTrack(ctx context.Context) tea.Cmd {
+	return a.commandCmd(ctx, "skipping to previous track", constant.EventTrackChanged{}, []string{"previous"})
+}
+
+func (a *mpdBridge) SetVolume(ctx context.Context, volume int) tea.Cmd {
+	if volume < 0 || volume > 100 {
+		return util.ToTeaCmdMsg[error](fmt.Errorf("volume must be between 0 and 100"))
+	}
+	return a.commandCmd(ctx, "setting volume", nil, []string{"setvol", strconv.Itoa(volume)})
+}
+
+func (a *mpdBridge) IncreaseVolume(ctx context.Context) tea.Cmd {
+	return a.changeVolume(ctx, 10)
+}
+
+func (a *mpdBridge) DecreaseVolume(ctx context.Context) tea.Cmd {
+	return a.changeVolume(ctx, -10)
+}
+
+func (a *mpdBridge) changeVolume(ctx context.Context, delta int) tea.Cmd {
+	return func() tea.Msg {
+		err := a.do(ctx, controlTimeout, func(c *mpdClient) error {
+			pairs, err := c.command("status")
+			if err != nil {
+				return err
+			}
+			volume := min(100, max(0, parseMpdStatus(pairs).volume+delta))
+			_, err = c.command("setvol", strconv.Itoa(volume))
+			return err
+		})
+		if err != nil {
+			a.log(fmt.Sprintf("Error changing volume: %v", err.Error()))
+			return err
+		}
+		return nil
+	}
+}
+
+// PlayPlaylist replace the queue with the stored playlist and play it.
+func (a *mpdBridge) PlayPlaylist(ctx context.Context, playlistName string) tea.Cmd {
+	return func() tea.Msg {
+		err := a.do(ctx, libraryTimeout, func(c *mpdClient) error {
+			return c.commandList([][]string{{"clear"}, {"load", playlistName}, {"play"}})
+		})
+		if err != nil {
+			err = mpdNotFound(err, ErrPlaylistNotFound)
+			a.log(fmt.Sprintf("Error playing playlist '%s': %v", playlistName, err.Error()))
+			return err
+		}
+		return constant.EventTrackChanged{}
+	}
+}
+
+// PlayTrackById play the song from the queue, a song which is not queued yet is appended to the queue.
+func (a *mpdBridge) PlayTrackById(ctx context.Context, id string) tea.Cmd {
+	return func() tea.Msg {
+		err := a.do(ctx, controlTimeout, func(c *mpdClient) error {
+			pairs, err := c.command("playlistfind", "file", id)
+			if err != nil {
+				return err
+			}
+			songId := mpdValue(pairs, "Id")
+			if songId == "" {
+				pairs, err = c.command("addid", id)
+				if err != nil {
+					return mpdNotFound(err, ErrTrackNotFound)
+				}
+				songId = mpdValue(pairs, "Id")
+			}
+			_, err = c.command("playid", songId)
+			return err
+		})
+		if err != nil {
+			a.log(fmt.Sprintf("Error play track byid: %v", err))
+			return err
+		}
+		return constant.EventTrackChanged{}
+	}
+}
+
+// PlayTrackInPlaylist play the song from the queue, which is the only playlist mpd play from.
+func (a *mpdBridge) PlayTrackInPlaylist(ctx context.Context, playlistId, trackId string) tea.Cmd {
+	return a.PlayTrackById(ctx, trackId)
+}
+
+func (a *mpdBridge) FavoriteCurrentTrack(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		track, err := a.GetCurrentTrack(ctx)
+		if err != nil {
+			a.log(fmt.Sprintf("Error favoriting track: %v", err.Error()))
+			return err
+		}
+		if track.Id == "" {
+			return fmt.Errorf("%w: no current track", ErrTrackNotFound)
+		}
+		return a.FavoriteTrackByTrackId(ctx, track.Id)()
+	}
+}
+
+// FavoriteTrackByTrackId toggle the favorite sticker of the song, which need the sticker database of mpd.
+func (a *mpdBridge) FavoriteTrackByTrackId(ctx context.Context, id string) tea.Cmd {
+	return func() tea.Msg {
+		err := a.do(ctx, controlTimeout, func(c *mpdClient) error {
+			favorited := a.favorites(c)[id]
+			var err error
+			if favorited {
+				_, err = c.command("sticker", "delete", "song", id, mpdFavoriteSticker)
+			} else {
+				_, err = c.command("sticker", "set", "song", id, mpdFavoriteSticker, "1")
+			}
+			return mpdNotFound(err, ErrTrackNotFound)
+		})
+		if err != nil {
+			a.log(fmt.Sprintf("Error favoriting track byid: %v", err))
+			return err
+		}
+		return constant.EventFavoriteTrackId(id)
+	}
+}
+
+func (a *mpdBridge) SetRating(ctx context.Context, trackId string, rating int) tea.Cmd {
+	return util.ToTeaCmdMsg[error](ErrNotSupported)
+}
+
+func (a *mpdBridge) SetDisliked(ctx context.Context, trackId string, disliked bool) tea.Cmd {
+	return util.ToTeaCmdMsg[error](ErrNotSupported)
+}
+
+func (a *mpdBridge) SetShuffle(ctx context.Context, enabled bool) tea.Cmd {
+	return a.commandCmd(ctx, "setting shuffle", constant.EventUpdateShuffle(enabled), []string{"random", mpdBool(enabled)})
+}
+
+// SetRepeat set the repeat and single modes, repeating one song is repeat with single.
+func (a *mpdBridge) SetRepeat(ctx context.Context, mode model.RepeatMode) tea.Cmd {
+	if !mode.Valid() {
+		return util.ToTeaCmdMsg[error](fmt.Errorf("unknown repeat mode: %s", mode))
+	}
+	return a.commandCmd(ctx, "setting repeat", constant.EventUpdateRepeat(mode),
+		[]string{"repeat", mpdBool(mode != model.RepeatOff)},
+		[]string{"single", mpdBool(mode == model.RepeatOne)},
+	)
+}
+
+func mpdBool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+func (a *mpdBridge) SetPlayerPosition(ctx context.Context, seconds int) tea.Cmd {
+	if seconds < 0 {
+		return util.ToTeaCmdMsg[error](fmt.Errorf("position must not be negative"))
+	}
+	return a.commandCmd(ctx, "setting player position", constant.EventPlayerPositionChanged(seconds), []string{"seekcur", strconv.Itoa(seconds)})
+}
+
+// Seek move the player position by deltaSeconds, a negative delta rewind.
+func (a *mpdBridge) Seek(ctx context.Context, deltaSeconds int) tea.Cmd {
+	return func() tea.Msg {
+		// a signed argument of seekcur is relative
+		offset := strconv.Itoa(deltaSeconds)
+		if deltaSeconds >= 0 {
+			offset = "+" + offset
+		}
+		var status mpdStatus
+		err := a.do(ctx, controlTimeout, func(c *mpdClient) error {
+			if _, err := c.command("seekcur", offset); err != nil {
+				return err
+			}
+			pairs, err := c.command("status")
+			status = parseMpdStatus(pairs)
+			return err
+		})
+		if err != nil {
+			a.log(fmt.Sprintf("Error seeking: %v", err.Error()))
+			return err
+		}
+		return constant.EventPlayerPositionChanged(int(status.elapsed))
+	}
+}
+
+// ======= playlist management, on the stored playlists
+
+// CreatePlaylist create an empty stored playlist, mpd can only save the queue so it is saved then cleared.
+func (a *mpdBridge) CreatePlaylist(ctx context.Context, name string) tea.Cmd {
+	return a.commandCmd(ctx, "creating playlist", constant.EventPlaylistCreated(model.Playlist{Id: name, Name: name}),
+		[]string{"save", name},
+		[]string{"playlistclear", name},
+	)
+}
+
+func (a *mpdBridge) RenamePlaylist(ctx context.Context, playlistId, name string) tea.Cmd {
+	return func() tea.Msg {
+		if _, err := a.command(ctx, libraryTimeout, "rename", playlistId, name); err != nil {
+			err = mpdNotFound(err, ErrPlaylistNotFound)
+			a.log(fmt.Sprintf("Error renaming playlist: %v", err.Error()))
+			return err
+		}
+		return constant.EventPlaylistsChanged{}
+	}
+}
+
+func (a *mpdBridge) DeletePlaylist(ctx context.Context, playlistId string) tea.Cmd {
+	return func() tea.Msg {
+		if _, err := a.command(ctx, libraryTimeout, "rm", playlistId); err != nil {
+			err = mpdNotFound(err, ErrPlaylistNotFound)
+			a.log(fmt.Sprintf("Error deleting playlist: %v", err.Error()))
+			return err
+		}
+		return constant.EventPlaylistsChanged{}
+	}
+}
+
+// AddTracksToPlaylist append the songs to the end of the stored playlist.
+func (a *mpdBridge) AddTracksToPlaylist(ctx context.Context, playlistId string, trackIds []string) tea.Cmd {
+	return func() tea.Msg {
+		commands := [][]string{}
+		for _, id := range trackIds {
+			commands = append(commands, []string{"playlistadd", playlistId, id})
+		}
+		err := a.do(ctx, libraryTimeout, func(c *mpdClient) error {
+			return c.commandList(commands)
+		})
+		if err != nil {
+			err = mpdNotFound(err, ErrTrackNotFound)
+			a.log(fmt.Sprintf("Error adding tracks to playlist: %v", err.Error()))
+			return err
+		}
+		return constant.EventPlaylistChanged(playlistId)
+	}
+}
+
+// RemoveTracksFromPlaylist remove every entry of the songs from the stored playlist.
+func (a *mpdBridge) RemoveTracksFromPlaylist(ctx context.Context, playlistId string, trackIds []string) tea.Cmd {
+	return func() tea.Msg {
+		err := a.do(ctx, libraryTimeout, func(c *mpdClient) error {
+			pairs, err := c.command("listplaylist", playlistId)
+			if err != nil {
+				return mpdNotFound(err, ErrPlaylistNotFound)
+			}
+			// delete from the end, so the positions left are still right
+			commands := [][]string{}
+			for pos := len(pairs) - 1; pos >= 0; pos-- {
+				if pairs[pos].key == "file" && slices.Contains(trackIds, pairs[pos].value) {
+					commands = append(commands, []string{"playlistdelete", playlistId, strconv.Itoa(pos)})
+				}
+			}
+			return c.commandList(commands)
+		})
+		if err != nil {
+			a.log(fmt.Sprintf("Error removing tracks from playlist: %v", err.Error()))
+			return err
+		}
+		return constant.EventPlaylistChanged(playlistId)
+	}
+}
+
+// ReorderPlaylist put the songs of the stored playlist in the order of trackIds, which should hold all of them.
+func (a *mpdBridge) ReorderPlaylist(ctx context.Context, playlistId string, trackIds []string) tea.Cmd {
+	return func() tea.Msg {
+		commands := [][]string{{"playlistclear", playlistId}}
+		for _, id := range trackIds {
+			commands = append(commands, []string{"playlistadd", playlistId, id})
+		}
+		err := a.do(ctx, libraryTimeout, func(c *mpdClient) error {
+			pairs, err := c.command("listplaylist", playlistId)
+			if err != nil {
+				return mpdNotFound(err, ErrPlaylistNotFound)
+			}
+			current := []string{}
+			for _, p := range pairs {
+				if p.key == "file" {
+					current = append(current, p.value)
+				}
+			}
+			if err := checkReorder(playlistId, current, trackIds); err != nil {
+				return err
+			}
+			return c.commandList(commands)
+		})
+		if err != nil {
+			err = mpdNotFound(err, ErrTrackNotFound)
+			a.log(fmt.Sprintf("Error reordering playlist: %v", err.Error()))
+			return err
+		}
+		return constant.EventPlaylistChanged(playlistId)
+	}
+}
+
+// ======= getters
+
+// GetNowPlaying read status and currentsong on the same connection.
+func (a *mpdBridge) GetNowPlaying(ctx context.Context) (model.NowPlaying, error) {
+	nowPlaying := model.NowPlaying{
+		Track:  model.Track{Name: "No Track Playing"},
+		State:  model.PlayerNotRunning,
+		Repeat: model.RepeatOff,
+	}
+	var status mpdStatus
+	var tracks []model.Track
+	err := a.do(ctx, queryTimeout, func(c *mpdClient) error {
+		pairs, err := c.command("status")
+		if err != nil {
+			return err
+		}
+		status = parseMpdStatus(pairs)
+		pairs, err = c.command("currentsong")
+		if err != nil {
+			return err
+		}
+		tracks = mpdSongs(pairs)
+		if len(tracks) > 0 {
+			tracks[0].Favorited = a.favorites(c)[tracks[0].Id]
+		}
+		return nil
+	})
+	if errors.Is(err, ErrAppNotRunning) {
+		return nowPlaying, nil
+	}
+	if err != nil {
+		return model.NowPlaying{}, fmt.Errorf("error getting now playing: %w", err)
+	}
+
+	nowPlaying.State = status.state
+	nowPlaying.Volume = status.volume
+	nowPlaying.Shuffle = status.shuffle
+	nowPlaying.Repeat = status.repeat
+	nowPlaying.Position = status.elapsed
+	if len(tracks) > 0 {
+		nowPlaying.Track = tracks[0]
+	}
+	nowPlaying.Playlist = mpdQueue(status)
+	return nowPlaying, nil
+}
+
+// mpdQueue return the identity of the queue
+func mpdQueue(status mpdStatus) model.Playlist {
+	return model.Playlist{
+		Id:         mpdQueueId,
+		Name:       "Queue",
+		TrackCount: status.playlistLength,
+		Stamp:      status.playlist,
+	}
+}
+
+func (a *mpdBridge) GetPlayerState(ctx context.Context) (model.PlayerState, error) {
+	status, err := a.status(ctx, queryTimeout)
+	if errors.Is(err, ErrAppNotRunning) {
+		return model.PlayerNotRunning, nil
+	}
+	if err != nil {
+		return model.PlayerStopped, fmt.Errorf("error getting player state: %w", err)
+	}
+	return status.state, nil
+}
+
+func (a *mpdBridge) GetShuffle(ctx context.Context) (bool, error) {
+	status, err := a.status(ctx, queryTimeout)
+	if err != nil {
+		return false, fmt.Errorf("error getting shuffle: %w", err)
+	}
+	return status.shuffle, nil
+}
+
+func (a *mpdBridge) GetRepeat(ctx context.Context) (model.RepeatMode, error) {
+	status, err := a.status(ctx, queryTimeout)
+	if err != nil {
+		return model.RepeatOff, fmt.Errorf("error getting repeat: %w", err)
+	}
+	return status.repeat, nil
+}
+
+func (a *mpdBridge) GetPlayerPosition(ctx context.Context) (int, error) {
+	status, err := a.status(ctx, queryTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("error getting player position: %w", err)
+	}
+	return int(status.elapsed), nil
+}
+
+// GetArtwork read the cover next to the song with albumart, a chunk at a time.
+func (a *mpdBridge) GetArtwork(ctx context.Context, track model.Track) (string, error) {
+	key := artwork.Key(track)
+	if key == "" {
+		return "", nil
+	}
+	if path, ok := a.artworks.Lookup(key); ok {
+		return path, nil
+	}
+
+	var data []byte
+	err := a.do(ctx, libraryTimeout, func(c *mpdClient) error {
+		for {
+			pairs, chunk, err := c.binaryCommand("albumart", track.Id, strconv.Itoa(len(data)))
+			if err != nil {
+				return err
+			}
+			data = append(data, chunk...)
+			size, err := strconv.Atoi(mpdValue(pairs, "size"))
+			if err != nil {
+				return &ParseError{Output: mpdValue(pairs, "size"), Err: err}
+			}
+			if len(chunk) == 0 || len(data) >= size {
+				return nil
+			}
+		}
+	})
+	var ackErr *MpdError
+	if errors.As(err, &ackErr) && ackErr.Code == mpdAckNoExist {
+		return "", nil
+	}
+	if err != nil {
+		a.log(fmt.Sprintf("Error getting artwork: %v", err.Error()))
+		return "", fmt.Errorf("error getting artwork: %w", err)
+	}
+
+	path, err := a.artworks.Put(key, func(path string) error {
+		return os.WriteFile(path, data, 0o644)
+	})
+	if err != nil {
+		return "", fmt.Errorf("error getting artwork: %w", err)
+	}
+	return path, nil
+}
+
+func (a *mpdBridge) GetCurrentTrack(ctx context.Context) (model.Track, error) {
+	nullTrack := model.Track{Name: "No Track Playing"}
+	tracks, err := a.songs(ctx, queryTimeout, "currentsong")
+	if errors.Is(err, ErrAppNotRunning) {
+		return nullTrack, nil
+	}
+	if err != nil {
+		return nullTrack, fmt.Errorf("error getting current track: %w", err)
+	}
+	if len(tracks) == 0 {
+		return nullTrack, nil
+	}
+	return tracks[0], nil
+}
+
+// GetPlaylists return the stored playlists.
+func (a *mpdBridge) GetPlaylists(ctx context.Context) ([]model.Playlist, error) {
+	pairs, err := a.command(ctx, libraryTimeout, "listplaylists")
+	if err != nil {
+		return nil, fmt.Errorf("error getting playlists: %w", err)
+	}
+	playlists := []model.Playlist{}
+	for _, p := range pairs {
+		if p.key == "playlist" {
+			playlists = append(playlists, model.Playlist{Id: p.value, Name: p.value})
+		}
+	}
+	return playlists, nil
+}
+
+// GetUserPlaylists return the stored playlists, all of them can be edited.
+func (a *mpdBridge) GetUserPlaylists(ctx context.Context) ([]model.Playlist, error) {
+	return a.GetPlaylists(ctx)
+}
+
+func (a *mpdBridge) GetCurrentPlaylistInfo(ctx context.Context) (model.Playlist, error) {
+	status, err := a.status(ctx, queryTimeout)
+	if err != nil {
+		return model.Playlist{}, fmt.Errorf("error getting current playlist: %w", err)
+	}
+	return mpdQueue(status), nil
+}
+
+// GetCurrentPlaylistTracks return up to limit songs of the queue starting at offset.
+func (a *mpdBridge) GetCurrentPlaylistTracks(ctx context.Context, offset, limit int) ([]model.Track, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, fmt.Errorf("invalid page: offset %d, limit %d", offset, limit)
+	}
+	window := strconv.Itoa(offset) + ":" + strconv.Itoa(offset+limit)
+	tracks, err := a.songs(ctx, libraryTimeout, "playlistinfo", window)
+	var ackErr *MpdError
+	if errors.As(err, &ackErr) && ackErr.Code == mpdAckArg { // the window start after the queue
+		return []model.Track{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting current playlist tracks: %w", err)
+	}
+	return tracks, nil
+}
+
+func (a *mpdBridge) GetTrackById(ctx context.Context, id string) (model.Track, error) {
+	tracks, err := a.songs(ctx, libraryTimeout, "lsinfo", id)
+	if err != nil {
+		return model.Track{}, fmt.Errorf("error getting track: %w", mpdNotFound(err, ErrTrackNotFound))
+	}
+	for _, track := range tracks {
+		if track.Id == id {
+			return track, nil
+		}
+	}
+	return model.Track{}, fmt.Errorf("%w: %s", ErrTrackNotFound, id)
+}
+
+// SearchLibrary search the database of mpd, case insensitive, at most searchLimit songs are returned.
+func (a *mpdBridge) SearchLibrary(ctx context.Context, query string, field model.SearchField) ([]model.Track, error) {
+	if !field.Valid() {
+		return nil, fmt.Errorf("unknown search field: %s", field)
+	}
+	if strings.TrimSpace(query) == "" {
+		return []model.Track{}, nil
+	}
+
+	tag := "any"
+	switch field {
+	case model.SearchSongs:
+		tag = "title"
+	case model.SearchArtists:
+		tag = "artist"
+	case model.SearchAlbums:
+		tag = "album"
+	}
+	tracks, err := a.songs(ctx, libraryTimeout, "search", tag, query, "window", "0:"+strconv.Itoa(searchLimit))
+	if err != nil {
+		return nil, fmt.Errorf("error searching library: %w", err)
+	}
+	return tracks, nil
+}
+
+// ======= Watcher
+
+// WatchChanges wait with the idle command until mpd report a change.
+// A lost connection is retried after mpdRetryDelay, nothing is reported once ctx is done.
+func (a *mpdBridge) WatchChanges(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		for {
+			changed, err := a.idle(ctx)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err == nil {
+				return constant.EventPlayerChanged(changed)
+			}
+			a.log(fmt.Sprintf("Error watching mpd: %v", err.Error()))
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(mpdRetryDelay):
+			}
+		}
+	}
+}
+
+// idle block until one of the subsystems the TUI show changed, and return them
+func (a *mpdBridge) idle(ctx context.Context) ([]string, error) {
+	c, err := dialMpd(ctx, a.addr, a.password)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	pairs, err := c.command("idle", "player", "mixer", "options", "playlist", "stored_playlist", "sticker")
+	if err != nil {
+		return nil, err
+	}
+	changed := []string{}
+	for _, p := range pairs {
+		if p.key == "changed" {
+			changed = append(changed, p.value)
+		}
+	}
+	return changed, nil
+}
@@ -0,0 +1,321 @@
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"limiu82214/lazyAppleMusic/internal/artwork"
+	"limiu82214/lazyAppleMusic/internal/constant"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMpd is a local mpd answering scripted responses, keyed by the command line as sent
+type fakeMpd struct {
+	addr string
+
+	mu        sync.Mutex
+	responses map[string]string
+	commands  []string
+	changes   chan string // answer the idle command
+}
+
+func startFakeMpd(t *testing.T) *fakeMpd {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	s := &fakeMpd{
+		addr:      l.Addr().String(),
+		responses: map[string]string{},
+		changes:   make(chan string, 1),
+	}
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		close(done)
+		l.Close()
+	})
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, done)
+		}
+	}()
+	return s
+}
+
+// on script the response of a command, without the final OK. A response starting with ACK is an error.
+func (s *fakeMpd) on(command, response string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[command] = response
+}
+
+func (s *fakeMpd) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+func (s *fakeMpd) serve(conn net.Conn, done chan struct{}) {
+	defer conn.Close()
+	io.WriteString(conn, "OK MPD 0.23.5\n")
+	r := bufio.NewReader(conn)
+	var list []string
+	inList := false
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "command_list_begin":
+			inList, list = true, nil
+		case line == "command_list_end":
+			inList = false
+			response := "OK\n"
+			for _, command := range list {
+				if answer := s.answer(command); strings.HasPrefix(answer, "ACK") {
+					response = answer
+					break
+				}
+			}
+			io.WriteString(conn, response)
+		case inList:
+			list = append(list, line)
+		case strings.HasPrefix(line, "idle"):
+			s.record(line)
+			select {
+			case changed := <-s.changes:
+				io.WriteString(conn, changed+"\nOK\n")
+			case <-done:
+				return
+			}
+		default:
+			io.WriteString(conn, s.answer(line))
+		}
+	}
+}
+
+func (s *fakeMpd) record(command string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = append(s.commands, command)
+}
+
+func (s *fakeMpd) answer(command string) string {
+	s.record(command)
+	s.mu.Lock()
+	response, ok := s.responses[command]
+	s.mu.Unlock()
+	if !ok {
+		name, _, _ := strings.Cut(command, " ")
+		return "ACK [5@0] {" + name + "} unknown command \"" + name + "\"\n"
+	}
+	if strings.HasPrefix(response, "ACK") {
+		return response + "\n"
+	}
+	return response + "OK\n"
+}
+
+func newTestMpdBridge(t *testing.T, s *fakeMpd) PlayerBridge {
+	t.Helper()
+	store, err := artwork.NewStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewMpdBridge(io.Discard, s.addr, "", store)
+}
+
+const testMpdSong = "file: Band/Album/02 Two.flac\n" +
+	"Title: Two\n" +
+	"Artist: Band\n" +
+	"Artist: Guest\n" +
+	"Album: Album\n" +
+	"Time: 200\n" +
+	"duration: 199.512\n" +
+	"Pos: 1\n" +
+	"Id: 12\n"
+
+func TestMpdGetNowPlaying(t *testing.T) {
+	s := startFakeMpd(t)
+	s.on("status", "volume: 40\nrepeat: 1\nrandom: 1\nsingle: 1\nplaylist: 7\nplaylistlength: 3\nstate: pause\nelapsed: 12.250\n")
+	s.on("currentsong", testMpdSong)
+	s.on(`sticker "find" "song" "" "favorite"`, "file: Band/Album/02 Two.flac\nsticker: favorite=1\n")
+
+	got, err := newTestMpdBridge(t, s).GetNowPlaying(context.Background())
+	if err != nil {
+		t.Fatalf("GetNowPlaying: %v", err)
+	}
+	want := model.NowPlaying{
+		Track: model.Track{
+			Id: "Band/Album/02 Two.flac", Name: "Two", Artist: "Band, Guest", Album: "Album",
+			Duration: 199.512, Time: "3:19", Favorited: true,
+		},
+		State:    model.PlayerPaused,
+		Position: 12.25,
+		Volume:   40,
+		Shuffle:  true,
+		Repeat:   model.RepeatOne,
+		Playlist: model.Playlist{Id: mpdQueueId, Name: "Queue", TrackCount: 3, Stamp: "7"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetNowPlaying\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestMpdNotRunning(t *testing.T) {
+	// nothing listen on the port of a closed listener
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	a := NewMpdBridge(io.Discard, addr, "", nil)
+
+	got, err := a.GetNowPlaying(context.Background())
+	if err != nil || got.State != model.PlayerNotRunning {
+		t.Errorf("GetNowPlaying = %q, %v, want not running without error", got.State, err)
+	}
+	if err, _ := a.PlayPause(context.Background())().(error); !errors.Is(err, ErrAppNotRunning) {
+		t.Errorf("PlayPause = %v, want ErrAppNotRunning", err)
+	}
+}
+
+func TestMpdCurrentPlaylistTracks(t *testing.T) {
+	s := startFakeMpd(t)
+	s.on(`playlistinfo "0:2"`, "file: a.flac\nTitle: A\nTime: 61\nfile: b/c.ogg\nTime: 5\n")
+	s.on(`playlistinfo "10:12"`, "ACK [2@0] {playlistinfo} Bad song index")
+	s.on(`sticker "find" "song" "" "favorite"`, "file: b/c.ogg\nsticker: favorite=1\n")
+	a := newTestMpdBridge(t, s)
+
+	tracks, err := a.GetCurrentPlaylistTracks(context.Background(), 0, 2)
+	if err != nil {
+		t.Fatalf("GetCurrentPlaylistTracks: %v", err)
+	}
+	want := []model.Track{
+		{Id: "a.flac", Name: "A", Duration: 61, Time: "1:01"},
+		// a song without title is named after its file
+		{Id: "b/c.ogg", Name: "c.ogg", Duration: 5, Time: "0:05", Favorited: true},
+	}
+	if !reflect.DeepEqual(tracks, want) {
+		t.Errorf("GetCurrentPlaylistTracks\n got %+v\nwant %+v", tracks, want)
+	}
+
+	// a page after the end of the queue is empty, not an error
+	tracks, err = a.GetCurrentPlaylistTracks(context.Background(), 10, 2)
+	if err != nil || len(tracks) != 0 {
+		t.Errorf("GetCurrentPlaylistTracks after the end = %v, %v", tracks, err)
+	}
+}
+
+func TestMpdGetArtwork(t *testing.T) {
+	s := startFakeMpd(t)
+	// the cover come in chunks, the data of every chunk is followed by a newline
+	s.on(`albumart "Band/Album/02 Two.flac" "0"`, "size: 10\nbinary: 6\n\x89PNG\r\n\n")
+	s.on(`albumart "Band/Album/02 Two.flac" "6"`, "size: 10\nbinary: 4\nOK\n\x00\n")
+	s.on(`albumart "Single.flac" "0"`, "ACK [50@0] {albumart} No file exists")
+	a := newTestMpdBridge(t, s)
+
+	path, err := a.GetArtwork(context.Background(), model.Track{Id: "Band/Album/02 Two.flac", Album: "Album", Artist: "Band"})
+	if err != nil {
+		t.Fatalf("GetArtwork: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "\x89PNG\r\nOK\n\x00"; string(data) != want {
+		t.Errorf("artwork = %q, want %q", data, want)
+	}
+
+	// a song without cover has no artwork, and nothing is stored
+	path, err = a.GetArtwork(context.Background(), model.Track{Id: "Single.flac"})
+	if err != nil || path != "" {
+		t.Errorf("GetArtwork without cover = %q, %v", path, err)
+	}
+}
+
+func TestMpdErrors(t *testing.T) {
+	s := startFakeMpd(t)
+	s.on(`playlistfind "file" "missing.flac"`, "")
+	s.on(`addid "missing.flac"`, "ACK [50@0] {addid} No such directory")
+	s.on(`rename "My \"Mix\"" "New"`, "ACK [50@0] {rename} No such playlist")
+	s.on("clear", "")
+	s.on(`load "Gone"`, "ACK [50@1] {load} No such playlist")
+	s.on(`listplaylist "Mix"`, "file: a.flac\nfile: b.flac\n")
+	s.on("setvol \"30\"", "ACK [4@0] {setvol} you don't have permission for \"setvol\"")
+	a := newTestMpdBridge(t, s)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		msg  interface{}
+		want error
+	}{
+		{"PlayTrackById", a.PlayTrackById(ctx, "missing.flac")(), ErrTrackNotFound},
+		{"RenamePlaylist", a.RenamePlaylist(ctx, `My "Mix"`, "New")(), ErrPlaylistNotFound},
+		{"PlayPlaylist", a.PlayPlaylist(ctx, "Gone")(), ErrPlaylistNotFound},
+		{"SetVolume", a.SetVolume(ctx, 30)(), ErrPermissionDenied},
+		{"SetRating", a.SetRating(ctx, "a.flac", 60)(), ErrNotSupported},
+		{"ReorderPlaylist", a.ReorderPlaylist(ctx, "Mix", []string{"b.flac"})(), ErrPlaylistChanged},
+	}
+	for _, tt := range tests {
+		err, _ := tt.msg.(error)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.msg, tt.want)
+		}
+	}
+
+	var ackErr *MpdError
+	if _, err := a.GetTrackById(ctx, "nowhere.flac"); !errors.As(err, &ackErr) || ackErr.Command != "lsinfo" {
+		t.Errorf("GetTrackById = %v, want the ack of lsinfo", err)
+	}
+}
+
+func TestMpdWatchChanges(t *testing.T) {
+	s := startFakeMpd(t)
+	a := newTestMpdBridge(t, s).(Watcher)
+
+	msgs := make(chan interface{}, 1)
+	go func() { msgs <- a.WatchChanges(context.Background())() }()
+	s.changes <- "changed: playlist\nchanged: player"
+	select {
+	case msg := <-msgs:
+		want := constant.EventPlayerChanged{"playlist", "player"}
+		if !reflect.DeepEqual(msg, want) {
+			t.Errorf("WatchChanges = %#v, want %#v", msg, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("WatchChanges did not report the change")
+	}
+	if received := s.received(); len(received) != 1 || !strings.HasPrefix(received[0], `idle "player" "mixer"`) {
+		t.Errorf("commands = %q, want a single idle", received)
+	}
+
+	// nothing is reported once the TUI quit
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { msgs <- a.WatchChanges(ctx)() }()
+	cancel()
+	select {
+	case msg := <-msgs:
+		if msg != nil {
+			t.Errorf("WatchChanges after cancel = %#v, want nil", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("WatchChanges did not stop with its context")
+	}
+}
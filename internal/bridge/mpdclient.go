@@ -0,0 +1,234 @@
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// mpdClient speak the text protocol of mpd, see https://mpd.readthedocs.io/en/latest/protocol.html
+// A client is one connection, it is not safe for concurrent use.
+type mpdClient struct {
+	ctx  context.Context // the client only live for ctx
+	conn net.Conn
+	r    *bufio.Reader
+	stop func() bool
+}
+
+// mpdPair is a "key: value" line of a response, responses keep their order and repeat keys.
+type mpdPair struct {
+	key   string
+	value string
+}
+
+// MpdError is an error answered by mpd, like "ACK [50@0] {play} No such song".
+type MpdError struct {
+	Code    int
+	Command string
+	Message string
+}
+
+// ack codes of mpd, see enum ack in src/protocol/Ack.hxx
+const (
+	mpdAckArg        = 2
+	mpdAckPermission = 4
+	mpdAckUnknown    = 5
+	mpdAckNoExist    = 50
+)
+
+func (e *MpdError) Error() string {
+	return fmt.Sprintf("mpd: %s: %s", e.Command, e.Message)
+}
+
+// Unwrap return the bridge error the ack code stand for. A missing song or playlist
+// share the same code, callers tell them apart with mpdNotFound.
+func (e *MpdError) Unwrap() error {
+	switch e.Code {
+	case mpdAckPermission:
+		return ErrPermissionDenied
+	case mpdAckUnknown:
+		return ErrNotSupported
+	}
+	return nil
+}
+
+// mpdNotFound wrap err with notFound when mpd answered that the song or playlist does not exist
+func mpdNotFound(err error, notFound error) error {
+	var ackErr *MpdError
+	if errors.As(err, &ackErr) && ackErr.Code == mpdAckNoExist {
+		return fmt.Errorf("%w: %w", notFound, err)
+	}
+	return err
+}
+
+var mpdAckPattern = regexp.MustCompile(`^ACK \[(\d+)@\d+\] \{([^}]*)\} (.*)$`)
+
+// dialMpd connect to mpd at addr, a path is a unix socket. The connection is closed once ctx is done.
+func dialMpd(ctx context.Context, addr, password string) (*mpdClient, error) {
+	network := "tcp"
+	if strings.HasPrefix(addr, "/") || strings.HasPrefix(addr, "@") {
+		network = "unix"
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, mpdNetError(ctx, err)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrAppNotRunning, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c := &mpdClient{
+		ctx:  ctx,
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		stop: context.AfterFunc(ctx, func() { conn.Close() }),
+	}
+	greeting, err := c.r.ReadString('\n')
+	if err != nil {
+		c.Close()
+		return nil, mpdNetError(ctx, err)
+	}
+	if !strings.HasPrefix(greeting, "OK MPD ") {
+		c.Close()
+		return nil, &ParseError{Output: greeting, Err: errors.New("not a mpd server")}
+	}
+	if password != "" {
+		if _, err := c.command("password", password); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *mpdClient) Close() error {
+	c.stop()
+	return c.conn.Close()
+}
+
+// mpdNetError tell a deadline or a cancelled ctx from other connection errors
+func mpdNetError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrTimeout, ctx.Err())
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	return err
+}
+
+// mpdQuote return arg as a quoted argument
+func mpdQuote(arg string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(arg) + `"`
+}
+
+func mpdLine(name string, args ...string) string {
+	line := name
+	for _, arg := range args {
+		line += " " + mpdQuote(arg)
+	}
+	return line + "\n"
+}
+
+// command send a command and return the pairs of its response.
+func (c *mpdClient) command(name string, args ...string) ([]mpdPair, error) {
+	if _, err := io.WriteString(c.conn, mpdLine(name, args...)); err != nil {
+		return nil, mpdNetError(c.ctx, err)
+	}
+	pairs, _, err := c.response()
+	return pairs, err
+}
+
+// commandList send the commands at once, mpd stop at the first failing command.
+func (c *mpdClient) commandList(commands [][]string) error {
+	if len(commands) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("command_list_begin\n")
+	for _, command := range commands {
+		b.WriteString(mpdLine(command[0], command[1:]...))
+	}
+	b.WriteString("command_list_end\n")
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return mpdNetError(c.ctx, err)
+	}
+	_, _, err := c.response()
+	return err
+}
+
+// binaryCommand send a command answering a chunk of binary data, like albumart.
+func (c *mpdClient) binaryCommand(name string, args ...string) ([]mpdPair, []byte, error) {
+	if _, err := io.WriteString(c.conn, mpdLine(name, args...)); err != nil {
+		return nil, nil, mpdNetError(c.ctx, err)
+	}
+	return c.response()
+}
+
+// response read pairs until OK, a "binary: n" pair is followed by n bytes of data.
+func (c *mpdClient) response() ([]mpdPair, []byte, error) {
+	pairs := []mpdPair{}
+	var data []byte
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return nil, nil, mpdNetError(c.ctx, err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "OK" {
+			return pairs, data, nil
+		}
+		if strings.HasPrefix(line, "ACK ") {
+			match := mpdAckPattern.FindStringSubmatch(line)
+			if match == nil {
+				return nil, nil, &ParseError{Output: line, Err: errors.New("malformed ack")}
+			}
+			code, _ := strconv.Atoi(match[1])
+			return nil, nil, &MpdError{Code: code, Command: match[2], Message: match[3]}
+		}
+
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			return nil, nil, &ParseError{Output: line, Err: errors.New("malformed pair")}
+		}
+		if key == "binary" {
+			size, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, nil, &ParseError{Output: line, Err: err}
+			}
+			// the data end with a newline which is not counted
+			data = make([]byte, size+1)
+			if _, err := io.ReadFull(c.r, data); err != nil {
+				return nil, nil, mpdNetError(c.ctx, err)
+			}
+			data = data[:size]
+			continue
+		}
+		pairs = append(pairs, mpdPair{key: key, value: value})
+	}
+}
+
+// mpdValue return the value of the first pair with key
+func mpdValue(pairs []mpdPair, key string) string {
+	for _, p := range pairs {
+		if p.key == key {
+			return p.value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,815 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"limiu82214/lazyAppleMusic/internal/artwork"
+	"limiu82214/lazyAppleMusic/internal/constant"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"limiu82214/lazyAppleMusic/internal/util"
+	"math"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/godbus/dbus/v5"
+)
+
+// MPRIS names, see https://specifications.freedesktop.org/mpris-spec/latest/
+const (
+	mprisPrefix         = "org.mpris.MediaPlayer2."
+	mprisPath           = dbus.ObjectPath("/org/mpris/MediaPlayer2")
+	mprisRootIface      = "org.mpris.MediaPlayer2"
+	mprisPlayerIface    = "org.mpris.MediaPlayer2.Player"
+	mprisTrackListIface = "org.mpris.MediaPlayer2.TrackList"
+	mprisPlaylistsIface = "org.mpris.MediaPlayer2.Playlists"
+)
+
+// mprisPlaylistLimit cap the playlists asked to the Playlists interface
+const mprisPlaylistLimit = 500
+
+// mprisBridge drive a MPRIS player (Spotify, mpv, VLC, Rhythmbox, ...) over D-Bus.
+// MPRIS has no favorites, ratings nor editable playlists, those return ErrNotSupported.
+// The current playlist is the track list of the player, or only the current track
+// when the player has no track list.
+type mprisBridge struct {
+	dump     io.Writer
+	conn     *dbus.Conn
+	player   string
+	artworks *artwork.Store
+
+	mu          sync.Mutex
+	artUrls     map[string]string // mpris:artUrl of the tracks seen in the metadata, by track id
+	artUrlOrder []string          // ids of artUrls, least recently seen first
+	name        string            // bus name of the player, empty until found
+}
+
+// mprisArtUrlsSize is the number of art urls kept, enough for the current track and a page of the track list.
+const mprisArtUrlsSize = 512
+
+// NewMprisBridge create a bridge driving the MPRIS player on conn, usually the session bus.
+// player is the bus name of the player, with or without "org.mpris.MediaPlayer2." (like "spotify"),
+// an empty player drive the first player found.
+func NewMprisBridge(dump io.Writer, conn *dbus.Conn, player string, artworks *artwork.Store) PlayerBridge {
+	return &mprisBridge{
+		dump:     dump,
+		conn:     conn,
+		player:   strings.TrimPrefix(player, mprisPrefix),
+		artworks: artworks,
+		artUrls:  map[string]string{},
+	}
+}
+
+func (a *mprisBridge) log(msg interface{}) {
+	if a.dump != nil {
+		spew.Fdump(a.dump, msg)
+	}
+}
+
+// ======= D-Bus
+
+// busName return the bus name of the player, ErrAppNotRunning when there is none.
+// The name is remembered, call look it up again once the player behind it is gone.
+func (a *mprisBridge) busName(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	name := a.name
+	a.mu.Unlock()
+	if name != "" {
+		return name, nil
+	}
+
+	names := []string{}
+	if err := a.conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.ListNames", 0).Store(&names); err != nil {
+		return "", mprisError(err)
+	}
+	sort.Strings(names)
+	want := mprisPrefix + a.player
+	for _, name := range names {
+		if !strings.HasPrefix(name, mprisPrefix) {
+			continue
+		}
+		// players running several instances add a suffix, like org.mpris.MediaPlayer2.vlc.instance42
+		if a.player == "" || name == want || strings.HasPrefix(name, want+".") {
+			a.mu.Lock()
+			a.name = name
+			a.mu.Unlock()
+			return name, nil
+		}
+	}
+	return "", ErrAppNotRunning
+}
+
+// forgetBusName make the next call look for the player again
+func (a *mprisBridge) forgetBusName(name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.name == name {
+		a.name = ""
+	}
+}
+
+// call a method of the player within timeout and store its results into ret
+func (a *mprisBridge) call(ctx context.Context, timeout time.Duration, method string, args []interface{}, ret ...interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	name, err := a.busName(ctx)
+	if err != nil {
+		return err
+	}
+	call := a.conn.Object(name, mprisPath).CallWithContext(ctx, method, 0, args...)
+	if errors.Is(mprisError(call.Err), ErrAppNotRunning) {
+		// the player quit, or was started again under another instance name
+		a.forgetBusName(name)
+		if name, err = a.busName(ctx); err != nil {
+			return err
+		}
+		call = a.conn.Object(name, mprisPath).CallWithContext(ctx, method, 0, args...)
+	}
+	if call.Err != nil {
+		return mprisError(call.Err)
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+	if err := call.Store(ret...); err != nil {
+		return &ParseError{Output: fmt.Sprint(call.Body...), Err: err}
+	}
+	return nil
+}
+
+func (a *mprisBridge) getAll(ctx context.Context, timeout time.Duration, iface string) (map[string]dbus.Variant, error) {
+	props := map[string]dbus.Variant{}
+	err := a.call(ctx, timeout, "org.freedesktop.DBus.Properties.GetAll", []interface{}{iface}, &props)
+	return props, err
+}
+
+func (a *mprisBridge) get(ctx context.Context, timeout time.Duration, iface, prop string) (dbus.Variant, error) {
+	var value dbus.Variant
+	err := a.call(ctx, timeout, "org.freedesktop.DBus.Properties.Get", []interface{}{iface, prop}, &value)
+	return value, err
+}
+
+func (a *mprisBridge) set(ctx context.Context, timeout time.Duration, prop string, value interface{}) error {
+	return a.call(ctx, timeout, "org.freedesktop.DBus.Properties.Set", []interface{}{mprisPlayerIface, prop, dbus.MakeVariant(value)})
+}
+
+// mprisError map the errors of D-Bus onto the errors of the bridges
+func mprisError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	var dbusErr dbus.Error
+	if !errors.As(err, &dbusErr) {
+		return err
+	}
+	switch dbusErr.Name {
+	case "org.freedesktop.DBus.Error.ServiceUnknown", "org.freedesktop.DBus.Error.NameHasNoOwner":
+		return fmt.Errorf("%w: %w", ErrAppNotRunning, err)
+	case "org.freedesktop.DBus.Error.AccessDenied":
+		return fmt.Errorf("%w: %w", ErrPermissionDenied, err)
+	case "org.freedesktop.DBus.Error.NoReply", "org.freedesktop.DBus.Error.Timeout":
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	case "org.freedesktop.DBus.Error.UnknownMethod", "org.freedesktop.DBus.Error.UnknownInterface",
+		"org.freedesktop.DBus.Error.UnknownProperty", "org.freedesktop.DBus.Error.NotSupported",
+		"org.freedesktop.DBus.Error.PropertyReadOnly":
+		return fmt.Errorf("%w: %w", ErrNotSupported, err)
+	}
+	return err
+}
+
+// ======= metadata
+
+// trackFromMetadata turn the xesam/mpris metadata of a track into a Track, the art url is remembered for GetArtwork
+func (a *mprisBridge) trackFromMetadata(metadata map[string]dbus.Variant) model.Track {
+	track := model.Track{
+		Id:          variantString(metadata["mpris:trackid"]),
+		Name:        variantString(metadata["xesam:title"]),
+		Artist:      strings.Join(variantStrings(metadata["xesam:artist"]), ", "),
+		Album:       variantString(metadata["xesam:album"]),
+		AlbumArtist: strings.Join(variantStrings(metadata["xesam:albumArtist"]), ", "),
+		PlayedCount: int(variantInt(metadata["xesam:useCount"])),
+		Rating:      int(math.Round(variantFloat(metadata["xesam:userRating"]) * 100)),
+		Lyrics:      variantString(metadata["xesam:asText"]),
+		Duration:    float64(variantInt(metadata["mpris:length"])) / 1e6, // microseconds
+	}
+	track.Time = formatTrackTime(track.Duration)
+	if track.Name == "" {
+		// players like mpv leave the title out for files without tags
+		if u, err := url.Parse(variantString(metadata["xesam:url"])); err == nil && u.Path != "" {
+			track.Name = path.Base(u.Path)
+		}
+	}
+
+	if artUrl := variantString(metadata["mpris:artUrl"]); artUrl != "" && track.Id != "" {
+		a.rememberArtUrl(track.Id, artUrl)
+	}
+	return track
+}
+
+// rememberArtUrl keep the art url of the track, the least recently seen are dropped past mprisArtUrlsSize
+func (a *mprisBridge) rememberArtUrl(trackId, artUrl string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.artUrls[trackId]; ok {
+		a.artUrlOrder = slices.DeleteFunc(a.artUrlOrder, func(id string) bool { return id == trackId })
+	}
+	a.artUrls[trackId] = artUrl
+	a.artUrlOrder = append(a.artUrlOrder, trackId)
+	for len(a.artUrlOrder) > mprisArtUrlsSize {
+		delete(a.artUrls, a.artUrlOrder[0])
+		a.artUrlOrder = a.artUrlOrder[1:]
+	}
+}
+
+func variantString(v dbus.Variant) string {
+	switch value := v.Value().(type) {
+	case string:
+		return value
+	case dbus.ObjectPath:
+		return string(value)
+	}
+	return ""
+}
+
+// variantStrings read a list of strings, some players send a single string instead
+func variantStrings(v dbus.Variant) []string {
+	switch value := v.Value().(type) {
+	case []string:
+		return value
+	case string:
+		return []string{value}
+	}
+	return nil
+}
+
+// variantInt read any integer, players do not agree on the integer types
+func variantInt(v dbus.Variant) int64 {
+	switch value := v.Value().(type) {
+	case int64:
+		return value
+	case uint64:
+		return int64(value)
+	case int32:
+		return int64(value)
+	case uint32:
+		return int64(value)
+	case int16:
+		return int64(value)
+	case uint16:
+		return int64(value)
+	case byte:
+		return int64(value)
+	case float64:
+		return int64(value)
+	}
+	return 0
+}
+
+func variantFloat(v dbus.Variant) float64 {
+	if value, ok := v.Value().(float64); ok {
+		return value
+	}
+	return float64(variantInt(v))
+}
+
+func variantBool(v dbus.Variant) bool {
+	value, _ := v.Value().(bool)
+	return value
+}
+
+func playerStateFromStatus(status string) model.PlayerState {
+	switch status {
+	case "Playing":
+		return model.PlayerPlaying
+	case "Paused":
+		return model.PlayerPaused
+	default:
+		return model.PlayerStopped
+	}
+}
+
+func repeatFromLoopStatus(status string) model.RepeatMode {
+	switch status {
+	case "Track":
+		return model.RepeatOne
+	case "Playlist":
+		return model.RepeatAll
+	default:
+		return model.RepeatOff
+	}
+}
+
+func loopStatusFromRepeat(mode model.RepeatMode) string {
+	switch mode {
+	case model.RepeatOne:
+		return "Track"
+	case model.RepeatAll:
+		return "Playlist"
+	default:
+		return "None"
+	}
+}
+
+// ======= playback
+
+// LaunchApp start the player through D-Bus activation, which need the name of the player.
+func (a *mprisBridge) LaunchApp(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		if a.player == "" {
+			return fmt.Errorf("%w: choose the player to launch with --mpris-player", ErrNotSupported)
+		}
+		ctx, cancel := context.WithTimeout(ctx, launchTimeout)
+		defer cancel()
+		call := a.conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.StartServiceByName", 0, mprisPrefix+a.player, uint32(0))
+		if call.Err != nil {
+			err := mprisError(call.Err)
+			a.log(fmt.Sprintf("Error launching player: %v", err.Error()))
+			return err
+		}
+		return constant.EventPlayerStateChanged{}
+	}
+}
+
+// playerCmd call a method of the Player interface and report msg once done
+func (a *mprisBridge) playerCmd(ctx context.Context, method string, msg tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		if err := a.call(ctx, controlTimeout, mprisPlayerIface+"."+method, nil); err != nil {
+			a.log(fmt.Sprintf("Error calling %s: %v", method, err.Error()))
+			return err
+		}
+		return msg
+	}
+}
+
+func (a *mprisBridge) PlayPause(ctx context.Context) tea.Cmd {
+	return a.playerCmd(ctx, "PlayPause", constant.EventPlayerStateChanged{})
+}
+
+func (a *mprisBridge) Play(ctx context.Context) tea.Cmd {
+	return a.playerCmd(ctx, "Play", constant.EventPlayerStateChanged{})
+}
+
+func (a *mprisBridge) Pause(ctx context.Context) tea.Cmd {
+	return a.playerCmd(ctx, "Pause", constant.EventPlayerStateChanged{})
+}
+
+func (a *mprisBridge) NextTrack(ctx context.Context) tea.Cmd {
+	return a.playerCmd(ctx, "Next", constant.EventTrackChanged{})
+}
+
+func (a *mprisBridge) PreviousTrack(ctx context.Context) tea.Cmd {
+	return a.playerCmd(ctx, "Previous", constant.EventTrackChanged{})
+}
+
+// SetVolume set the volume, MPRIS volumes go from 0.0 to 1.0.
+func (a *mprisBridge) SetVolume(ctx context.Context, volume int) tea.Cmd {
+	return func() tea.Msg {
+		if volume < 0 || volume > 100 {
+			return fmt.Errorf("volume must be between 0 and 100")
+		}
+		if err := a.set(ctx, controlTimeout, "Volume", float64(volume)/100); err != nil {
+			a.log(fmt.Sprintf("Error setting volume: %v", err.Error()))
+			return err
+		}
+		return nil
+	}
+}
+
+func (a *mprisBridge) IncreaseVolume(ctx context.Context) tea.Cmd {
+	return a.changeVolume(ctx, 0.1)
+}
+
+func (a *mprisBridge) DecreaseVolume(ctx context.Context) tea.Cmd {
+	return a.changeVolume(ctx, -0.1)
+}
+
+func (a *mprisBridge) changeVolume(ctx context.Context, delta float64) tea.Cmd {
+	return func() tea.Msg {
+		current, err := a.get(ctx, controlTimeout, mprisPlayerIface, "Volume")
+		if err != nil {
+			a.log(fmt.Sprintf("Error getting volume: %v", err.Error()))
+			return err
+		}
+		volume := math.Min(1, math.Max(0, variantFloat(current)+delta))
+		if err := a.set(ctx, controlTimeout, "Volume", volume); err != nil {
+			a.log(fmt.Sprintf("Error setting volume: %v", err.Error()))
+			return err
+		}
+		return nil
+	}
+}
+
+// PlayPlaylist play the playlist of the Playlists interface named playlistName.
+func (a *mprisBridge) PlayPlaylist(ctx context.Context, playlistName string) tea.Cmd {
+	return func() tea.Msg {
+		playlists, err := a.GetPlaylists(ctx)
+		if err != nil {
+			a.log(fmt.Sprintf("Error playing playlist '%s': %v", playlistName, err.Error()))
+			return err
+		}
+		for _, p := range playlists {
+			if p.Name != playlistName {
+				continue
+			}
+			if err := a.call(ctx, controlTimeout, mprisPlaylistsIface+".ActivatePlaylist", []interface{}{dbus.ObjectPath(p.Id)}); err != nil {
+				a.log(fmt.Sprintf("Error playing playlist '%s': %v", playlistName, err.Error()))
+				return err
+			}
+			return constant.EventTrackChanged{}
+		}
+		return fmt.Errorf("%w: %s", ErrPlaylistNotFound, playlistName)
+	}
+}
+
+// PlayTrackById play the track of the track list.
+func (a *mprisBridge) PlayTrackById(ctx context.Context, id string) tea.Cmd {
+	return func() tea.Msg {
+		if !dbus.ObjectPath(id).IsValid() {
+			return fmt.Errorf("%w: %s", ErrTrackNotFound, id)
+		}
+		if err := a.call(ctx, controlTimeout, mprisTrackListIface+".GoTo", []interface{}{dbus.ObjectPath(id)}); err != nil {
+			a.log(fmt.Sprintf("Error play track byid: %v", err))
+			return err
+		}
+		return constant.EventTrackChanged{}
+	}
+}
+
+// PlayTrackInPlaylist play the track of the track list, which is the only playlist of a MPRIS player.
+func (a *mprisBridge) PlayTrackInPlaylist(ctx context.Context, playlistId, trackId string) tea.Cmd {
+	return a.PlayTrackById(ctx, trackId)
+}
+
+func (a *mprisBridge) FavoriteCurrentTrack(ctx context.Context) tea.Cmd {
+	return util.ToTeaCmdMsg[error](ErrNotSupported)
+}
+
+func (a *mprisBridge) FavoriteTrackByTrackId(ctx context.Context, id string) tea.Cmd {
+	return util.ToTeaCmdMsg[error](ErrNotSupported)
+}
+
+func (a *mprisBridge) SetRating(ctx context.Context, trackId string, rating int) tea.Cmd {
+	return util.ToTeaCmdMsg[error](ErrNotSupported)
+}
+
+func (a *mprisBridge) SetDisliked(ctx context.Context, trackId string, disliked bool) tea.Cmd {
+	return util.ToTeaCmdMsg[error](ErrNotSupported)
+}
+
+func (a *mprisBridge) SetShuffle(ctx context.Context, enabled bool) tea.Cmd {
+	return func() tea.Msg {
+		if err := a.set(ctx, controlTimeout, "Shuffle", enabled); err != nil {
+			a.log(fmt.Sprintf("Error setting shuffle: %v", err.Error()))
+			return err
+		}
+		return constant.EventUpdateShuffle(enabled)
+	}
+}
+
+func (a *mprisBridge) SetRepeat(ctx context.Context, mode model.RepeatMode) tea.Cmd {
+	return func() tea.Msg {
+		if !mode.Valid() {
+			return fmt.Errorf("unknown repeat mode: %s", mode)
+		}
+		if err := a.set(ctx, controlTimeout, "LoopStatus", loopStatusFromRepeat(mode)); err != nil {
+			a.log(fmt.Sprintf("Error setting repeat: %v", err.Error()))
+			return err
+		}
+		return constant.EventUpdateRepeat(mode)
+	}
+}
+
+// SetPlayerPosition move into the current track, SetPosition of MPRIS need the id of the track.
+func (a *mprisBridge) SetPlayerPosition(ctx context.Context, seconds int) tea.Cmd {
+	return func() tea.Msg {
+		if seconds < 0 {
+			return fmt.Errorf("position must not be negative")
+		}
+		track, err := a.GetCurrentTrack(ctx)
+		if err != nil {
+			a.log(fmt.Sprintf("Error setting player position: %v", err.Error()))
+			return err
+		}
+		if !dbus.ObjectPath(track.Id).IsValid() {
+			return fmt.Errorf("%w: no current track", ErrTrackNotFound)
+		}
+		position := int64(seconds) * int64(time.Second/time.Microsecond)
+		if err := a.call(ctx, controlTimeout, mprisPlayerIface+".SetPosition", []interface{}{dbus.ObjectPath(track.Id), position}); err != nil {
+			a.log(fmt.Sprintf("Error setting player position: %v", err.Error()))
+			return err
+		}
+		return constant.EventPlayerPositionChanged(seconds)
+	}
+}
+
+// Seek move the player position by deltaSeconds, a negative delta rewind.
+func (a *mprisBridge) Seek(ctx context.Context, deltaSeconds int) tea.Cmd {
+	return func() tea.Msg {
+		offset := int64(deltaSeconds) * int64(time.Second/time.Microsecond)
+		if err := a.call(ctx, controlTimeout, mprisPlayerIface+".Seek", []interface{}{offset}); err != nil {
+			a.log(fmt.Sprintf("Error seeking: %v", err.Error()))
+			return err
+		}
+		position, err := a.GetPlayerPosition(ctx)
+		if err != nil {
+			return err
+		}
+		return constant.EventPlayerPositionChanged(position)
+	}
+}
+
+// ======= playlist management, MPRIS playlists are read only
+
+func (a *mprisBridge) CreatePlaylist(ctx context.Context, name string) tea.Cmd {
+	return util.ToTeaCmdMsg[error](ErrNotSupported)
+}
+
+func (a *mprisBridge) RenamePlaylist(ctx context.Context, playlistId, name string) tea.Cmd {
+	return util.ToTeaCmdMsg[error](ErrNotSupported)
+}
+
+func (a *mprisBridge) DeletePlaylist(ctx context.Context, playlistId string) tea.Cmd {
+	return util.ToTeaCmdMsg[error](ErrNotSupported)
+}
+
+func (a *mprisBridge) AddTracksToPlaylist(ctx context.Context, playlistId string, trackIds []string) tea.Cmd {
+	return util.ToTeaCmdMsg[error](ErrNotSupported)
+}
+
+func (a *mprisBridge) RemoveTracksFromPlaylist(ctx context.Context, playlistId string, trackIds []string) tea.Cmd {
+	return util.ToTeaCmdMsg[error](ErrNotSupported)
+}
+
+func (a *mprisBridge) ReorderPlaylist(ctx context.Context, playlistId string, trackIds []string) tea.Cmd {
+	return util.ToTeaCmdMsg[error](ErrNotSupported)
+}
+
+// ======= getters
+
+// GetNowPlaying read the Player properties with a single call, so every field belong to the same track,
+// then the identity and the track list of the player: up to three calls once the bus name is known.
+func (a *mprisBridge) GetNowPlaying(ctx context.Context) (model.NowPlaying, error) {
+	nowPlaying := model.NowPlaying{
+		Track:  model.Track{Name: "No Track Playing"},
+		State:  model.PlayerNotRunning,
+		Repeat: model.RepeatOff,
+	}
+	props, err := a.getAll(ctx, queryTimeout, mprisPlayerIface)
+	if errors.Is(err, ErrAppNotRunning) {
+		return nowPlaying, nil
+	}
+	if err != nil {
+		return model.NowPlaying{}, fmt.Errorf("error getting now playing: %w", err)
+	}
+
+	nowPlaying.State = playerStateFromStatus(variantString(props["PlaybackStatus"]))
+	nowPlaying.Volume = int(math.Round(variantFloat(props["Volume"]) * 100))
+	nowPlaying.Shuffle = variantBool(props["Shuffle"])
+	nowPlaying.Repeat = repeatFromLoopStatus(variantString(props["LoopStatus"]))
+	nowPlaying.Position = float64(variantInt(props["Position"])) / 1e6
+	if metadata, ok := props["Metadata"].Value().(map[string]dbus.Variant); ok && len(metadata) > 0 {
+		nowPlaying.Track = a.trackFromMetadata(metadata)
+	}
+
+	playlist, _, err := a.trackList(ctx, nowPlaying.Track)
+	if err != nil {
+		return model.NowPlaying{}, fmt.Errorf("error getting now playing: %w", err)
+	}
+	nowPlaying.Playlist = playlist
+	return nowPlaying, nil
+}
+
+// trackList return the identity of the current playlist with the ids of its tracks. A player without
+// track list get a playlist holding only current, its stamp change with the track.
+func (a *mprisBridge) trackList(ctx context.Context, current model.Track) (model.Playlist, []dbus.ObjectPath, error) {
+	root, err := a.getAll(ctx, queryTimeout, mprisRootIface)
+	if err != nil {
+		return model.Playlist{}, nil, err
+	}
+	name, err := a.busName(ctx)
+	if err != nil {
+		return model.Playlist{}, nil, err
+	}
+	playlist := model.Playlist{Id: name, Name: variantString(root["Identity"])}
+
+	ids := []dbus.ObjectPath{}
+	if variantBool(root["HasTrackList"]) {
+		value, err := a.get(ctx, queryTimeout, mprisTrackListIface, "Tracks")
+		if err != nil {
+			return model.Playlist{}, nil, err
+		}
+		ids, _ = value.Value().([]dbus.ObjectPath)
+	} else if dbus.ObjectPath(current.Id).IsValid() {
+		ids = append(ids, dbus.ObjectPath(current.Id))
+	}
+
+	h := fnv.New64a()
+	for _, id := range ids {
+		h.Write([]byte(id + "\x00"))
+	}
+	playlist.TrackCount = len(ids)
+	playlist.Stamp = strconv.FormatUint(h.Sum64(), 36)
+	return playlist, ids, nil
+}
+
+func (a *mprisBridge) GetPlayerState(ctx context.Context) (model.PlayerState, error) {
+	status, err := a.get(ctx, queryTimeout, mprisPlayerIface, "PlaybackStatus")
+	if errors.Is(err, ErrAppNotRunning) {
+		return model.PlayerNotRunning, nil
+	}
+	if err != nil {
+		return model.PlayerStopped, fmt.Errorf("error getting player state: %w", err)
+	}
+	return playerStateFromStatus(variantString(status)), nil
+}
+
+func (a *mprisBridge) GetShuffle(ctx context.Context) (bool, error) {
+	shuffle, err := a.get(ctx, queryTimeout, mprisPlayerIface, "Shuffle")
+	if err != nil {
+		return false, fmt.Errorf("error getting shuffle: %w", err)
+	}
+	return variantBool(shuffle), nil
+}
+
+func (a *mprisBridge) GetRepeat(ctx context.Context) (model.RepeatMode, error) {
+	status, err := a.get(ctx, queryTimeout, mprisPlayerIface, "LoopStatus")
+	if err != nil {
+		return model.RepeatOff, fmt.Errorf("error getting repeat: %w", err)
+	}
+	return repeatFromLoopStatus(variantString(status)), nil
+}
+
+func (a *mprisBridge) GetPlayerPosition(ctx context.Context) (int, error) {
+	position, err := a.get(ctx, queryTimeout, mprisPlayerIface, "Position")
+	if err != nil {
+		return 0, fmt.Errorf("error getting player position: %w", err)
+	}
+	return int(variantInt(position) / 1e6), nil
+}
+
+// GetArtwork copy the artwork of track into the artwork store, only file:// art urls are supported.
+func (a *mprisBridge) GetArtwork(ctx context.Context, track model.Track) (string, error) {
+	key := artwork.Key(track)
+	if key == "" {
+		return "", nil
+	}
+	if path, ok := a.artworks.Lookup(key); ok {
+		return path, nil
+	}
+
+	a.mu.Lock()
+	artUrl := a.artUrls[track.Id]
+	a.mu.Unlock()
+	u, err := url.Parse(artUrl)
+	if err != nil || u.Scheme != "file" {
+		return "", nil
+	}
+
+	path, err := a.artworks.Put(key, func(path string) error {
+		data, err := os.ReadFile(u.Path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, data, 0o644)
+	})
+	if err != nil {
+		a.log(fmt.Sprintf("Error getting artwork: %v", err.Error()))
+		return "", fmt.Errorf("error getting artwork: %w", err)
+	}
+	return path, nil
+}
+
+func (a *mprisBridge) GetCurrentTrack(ctx context.Context) (model.Track, error) {
+	nullTrack := model.Track{Name: "No Track Playing"}
+	value, err := a.get(ctx, queryTimeout, mprisPlayerIface, "Metadata")
+	if errors.Is(err, ErrAppNotRunning) {
+		return nullTrack, nil
+	}
+	if err != nil {
+		return nullTrack, fmt.Errorf("error getting current track: %w", err)
+	}
+	metadata, ok := value.Value().(map[string]dbus.Variant)
+	if !ok || len(metadata) == 0 {
+		return nullTrack, nil
+	}
+	return a.trackFromMetadata(metadata), nil
+}
+
+// GetPlaylists return the playlists of the Playlists interface, empty when the player has none.
+func (a *mprisBridge) GetPlaylists(ctx context.Context) ([]model.Playlist, error) {
+	var found []struct {
+		Id   dbus.ObjectPath
+		Name string
+		Icon string
+	}
+	args := []interface{}{uint32(0), uint32(mprisPlaylistLimit), "Alphabetical", false}
+	err := a.call(ctx, libraryTimeout, mprisPlaylistsIface+".GetPlaylists", args, &found)
+	if errors.Is(err, ErrNotSupported) {
+		return []model.Playlist{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting playlists: %w", err)
+	}
+
+	playlists := make([]model.Playlist, 0, len(found))
+	for _, p := range found {
+		playlists = append(playlists, model.Playlist{Id: string(p.Id), Name: p.Name})
+	}
+	return playlists, nil
+}
+
+// GetUserPlaylists fail, no MPRIS playlist can be edited.
+func (a *mprisBridge) GetUserPlaylists(ctx context.Context) ([]model.Playlist, error) {
+	return nil, fmt.Errorf("error getting user playlists: %w", ErrNotSupported)
+}
+
+func (a *mprisBridge) GetCurrentPlaylistInfo(ctx context.Context) (model.Playlist, error) {
+	current, err := a.GetCurrentTrack(ctx)
+	if err != nil {
+		return model.Playlist{}, fmt.Errorf("error getting current playlist: %w", err)
+	}
+	playlist, _, err := a.trackList(ctx, current)
+	if err != nil {
+		return model.Playlist{}, fmt.Errorf("error getting current playlist: %w", err)
+	}
+	return playlist, nil
+}
+
+// GetCurrentPlaylistTracks return up to limit tracks of the track list starting at offset.
+func (a *mprisBridge) GetCurrentPlaylistTracks(ctx context.Context, offset, limit int) ([]model.Track, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, fmt.Errorf("invalid page: offset %d, limit %d", offset, limit)
+	}
+
+	current, err := a.GetCurrentTrack(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting current playlist tracks: %w", err)
+	}
+	_, ids, err := a.trackList(ctx, current)
+	if err != nil {
+		return nil, fmt.Errorf("error getting current playlist tracks: %w", err)
+	}
+	if offset >= len(ids) {
+		return []model.Track{}, nil
+	}
+	ids = ids[offset:min(offset+limit, len(ids))]
+	if len(ids) == 1 && string(ids[0]) == current.Id {
+		return []model.Track{current}, nil
+	}
+
+	tracks, err := a.tracksMetadata(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error getting current playlist tracks: %w", err)
+	}
+	return tracks, nil
+}
+
+func (a *mprisBridge) tracksMetadata(ctx context.Context, ids []dbus.ObjectPath) ([]model.Track, error) {
+	var metadata []map[string]dbus.Variant
+	if err := a.call(ctx, libraryTimeout, mprisTrackListIface+".GetTracksMetadata", []interface{}{ids}, &metadata); err != nil {
+		return nil, err
+	}
+	tracks := make([]model.Track, 0, len(metadata))
+	for _, m := range metadata {
+		tracks = append(tracks, a.trackFromMetadata(m))
+	}
+	return tracks, nil
+}
+
+func (a *mprisBridge) GetTrackById(ctx context.Context, id string) (model.Track, error) {
+	current, err := a.GetCurrentTrack(ctx)
+	if err != nil {
+		return model.Track{}, fmt.Errorf("error getting track: %w", err)
+	}
+	if current.Id == id {
+		return current, nil
+	}
+	if !dbus.ObjectPath(id).IsValid() {
+		return model.Track{}, fmt.Errorf("%w: %s", ErrTrackNotFound, id)
+	}
+
+	tracks, err := a.tracksMetadata(ctx, []dbus.ObjectPath{dbus.ObjectPath(id)})
+	if errors.Is(err, ErrNotSupported) || (err == nil && len(tracks) == 0) {
+		return model.Track{}, fmt.Errorf("%w: %s", ErrTrackNotFound, id)
+	}
+	if err != nil {
+		return model.Track{}, fmt.Errorf("error getting track: %w", err)
+	}
+	return tracks[0], nil
+}
+
+// SearchLibrary fail, MPRIS does not expose the library of the player.
+func (a *mprisBridge) SearchLibrary(ctx context.Context, query string, field model.SearchField) ([]model.Track, error) {
+	return nil, fmt.Errorf("error searching library: %w", ErrNotSupported)
+}
@@ -0,0 +1,371 @@
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"limiu82214/lazyAppleMusic/internal/constant"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// testBusAddress is the private session bus started by TestMain, empty when dbus-daemon is missing
+var testBusAddress string
+
+func TestMain(m *testing.M) {
+	stop := startTestBus()
+	code := m.Run()
+	stop()
+	os.Exit(code)
+}
+
+// startTestBus start a dbus-daemon of its own, so the MPRIS tests never see the players of the desktop
+func startTestBus() (stop func()) {
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		return func() {}
+	}
+	cmd := exec.Command("dbus-daemon", "--session", "--nofork", "--print-address=1")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return func() {}
+	}
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, "error starting dbus-daemon:", err)
+		return func() {}
+	}
+	stop = func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error reading the address of dbus-daemon:", err)
+		return stop
+	}
+	testBusAddress = strings.TrimSpace(address)
+	return stop
+}
+
+func connectTestBus(t *testing.T) *dbus.Conn {
+	t.Helper()
+	if testBusAddress == "" {
+		t.Skip("dbus-daemon is not installed")
+	}
+	conn, err := dbus.Connect(testBusAddress)
+	if err != nil {
+		t.Fatalf("connecting to the test bus: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// fakeMprisPlayer is a stand-in MPRIS player, its properties are plain maps and every call is recorded
+type fakeMprisPlayer struct {
+	conn *dbus.Conn
+
+	mu     sync.Mutex
+	props  map[string]map[string]dbus.Variant // by interface
+	tracks map[dbus.ObjectPath]map[string]dbus.Variant
+	calls  []string
+}
+
+func testMetadata(id, title string, artists []string, seconds int64) map[string]dbus.Variant {
+	return map[string]dbus.Variant{
+		"mpris:trackid": dbus.MakeVariant(dbus.ObjectPath(id)),
+		"xesam:title":   dbus.MakeVariant(title),
+		"xesam:artist":  dbus.MakeVariant(artists),
+		"xesam:album":   dbus.MakeVariant("Album"),
+		"mpris:length":  dbus.MakeVariant(seconds * 1e6),
+	}
+}
+
+// startFakeMprisPlayer export a player playing the second of three tracks under org.mpris.MediaPlayer2.<name>
+func startFakeMprisPlayer(t *testing.T, name string) *fakeMprisPlayer {
+	t.Helper()
+	tracks := []map[string]dbus.Variant{
+		testMetadata("/track/1", "One", []string{"Band"}, 100),
+		testMetadata("/track/2", "Two", []string{"Band", "Guest"}, 200),
+		testMetadata("/track/3", "Three", []string{"Other"}, 300),
+	}
+	p := &fakeMprisPlayer{
+		conn: connectTestBus(t),
+		props: map[string]map[string]dbus.Variant{
+			mprisRootIface: {
+				"Identity":     dbus.MakeVariant("Test Player"),
+				"HasTrackList": dbus.MakeVariant(true),
+			},
+			mprisPlayerIface: {
+				"PlaybackStatus": dbus.MakeVariant("Playing"),
+				"LoopStatus":     dbus.MakeVariant("Playlist"),
+				"Shuffle":        dbus.MakeVariant(false),
+				"Volume":         dbus.MakeVariant(0.5),
+				"Position":       dbus.MakeVariant(int64(12e6)),
+				"Metadata":       dbus.MakeVariant(tracks[1]),
+			},
+			mprisTrackListIface: {
+				"Tracks": dbus.MakeVariant([]dbus.ObjectPath{"/track/1", "/track/2", "/track/3"}),
+			},
+		},
+		tracks: map[dbus.ObjectPath]map[string]dbus.Variant{},
+	}
+	for _, track := range tracks {
+		p.tracks[track["mpris:trackid"].Value().(dbus.ObjectPath)] = track
+	}
+
+	exports := map[string]map[string]interface{}{
+		"org.freedesktop.DBus.Properties": {
+			"Get": func(iface, prop string) (dbus.Variant, *dbus.Error) {
+				p.record("Get " + prop)
+				p.mu.Lock()
+				defer p.mu.Unlock()
+				value, ok := p.props[iface][prop]
+				if !ok {
+					return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", []interface{}{prop})
+				}
+				return value, nil
+			},
+			"GetAll": func(iface string) (map[string]dbus.Variant, *dbus.Error) {
+				p.record("GetAll " + iface)
+				p.mu.Lock()
+				defer p.mu.Unlock()
+				return p.props[iface], nil
+			},
+			"Set": func(iface, prop string, value dbus.Variant) *dbus.Error {
+				p.record("Set " + prop)
+				p.setProp(iface, prop, value)
+				return nil
+			},
+		},
+		mprisPlayerIface: {
+			"PlayPause": func() *dbus.Error { p.record("PlayPause"); return nil },
+			"Next":      func() *dbus.Error { p.record("Next"); return nil },
+			"Seek": func(offset int64) *dbus.Error {
+				p.record(fmt.Sprintf("Seek %d", offset))
+				p.mu.Lock()
+				position := p.props[mprisPlayerIface]["Position"].Value().(int64)
+				p.mu.Unlock()
+				p.setProp(mprisPlayerIface, "Position", dbus.MakeVariant(position+offset))
+				return nil
+			},
+			"SetPosition": func(id dbus.ObjectPath, position int64) *dbus.Error {
+				p.record(fmt.Sprintf("SetPosition %s %d", id, position))
+				return nil
+			},
+		},
+		mprisTrackListIface: {
+			"GetTracksMetadata": func(ids []dbus.ObjectPath) ([]map[string]dbus.Variant, *dbus.Error) {
+				p.record(fmt.Sprintf("GetTracksMetadata %v", ids))
+				found := []map[string]dbus.Variant{}
+				for _, id := range ids {
+					if track, ok := p.tracks[id]; ok {
+						found = append(found, track)
+					}
+				}
+				return found, nil
+			},
+			"GoTo": func(id dbus.ObjectPath) *dbus.Error { p.record("GoTo " + string(id)); return nil },
+		},
+	}
+	for iface, methods := range exports {
+		if err := p.conn.ExportMethodTable(methods, mprisPath, iface); err != nil {
+			t.Fatalf("exporting %s: %v", iface, err)
+		}
+	}
+	reply, err := p.conn.RequestName(mprisPrefix+name, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("requesting %s: %v %v", mprisPrefix+name, reply, err)
+	}
+	return p
+}
+
+func (p *fakeMprisPlayer) record(call string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, call)
+}
+
+func (p *fakeMprisPlayer) setProp(iface, prop string, value dbus.Variant) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.props[iface][prop] = value
+}
+
+func (p *fakeMprisPlayer) prop(iface, prop string) interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.props[iface][prop].Value()
+}
+
+// takeCalls return the calls recorded since the last takeCalls
+func (p *fakeMprisPlayer) takeCalls() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	calls := p.calls
+	p.calls = nil
+	return calls
+}
+
+func newTestMprisBridge(t *testing.T, player string) PlayerBridge {
+	return NewMprisBridge(io.Discard, connectTestBus(t), player, nil)
+}
+
+func TestMprisGetNowPlaying(t *testing.T) {
+	player := startFakeMprisPlayer(t, "nowplaying")
+	a := newTestMprisBridge(t, "nowplaying")
+
+	got, err := a.GetNowPlaying(context.Background())
+	if err != nil {
+		t.Fatalf("GetNowPlaying: %v", err)
+	}
+	if got.State != model.PlayerPlaying || got.Volume != 50 || got.Shuffle || got.Repeat != model.RepeatAll || got.Position != 12 {
+		t.Errorf("GetNowPlaying player = %+v", got)
+	}
+	wantTrack := model.Track{Id: "/track/2", Name: "Two", Artist: "Band, Guest", Album: "Album", Duration: 200, Time: "3:20"}
+	if !reflect.DeepEqual(got.Track, wantTrack) {
+		t.Errorf("GetNowPlaying track\n got %+v\nwant %+v", got.Track, wantTrack)
+	}
+	if got.Playlist.Id != mprisPrefix+"nowplaying" || got.Playlist.Name != "Test Player" || got.Playlist.TrackCount != 3 || got.Playlist.Stamp == "" {
+		t.Errorf("GetNowPlaying playlist = %+v", got.Playlist)
+	}
+
+	// once the bus name is known, a poll is the Player properties, the identity and the track list
+	player.takeCalls()
+	if _, err := a.GetNowPlaying(context.Background()); err != nil {
+		t.Fatalf("GetNowPlaying: %v", err)
+	}
+	want := []string{"GetAll " + mprisPlayerIface, "GetAll " + mprisRootIface, "Get Tracks"}
+	if calls := player.takeCalls(); !reflect.DeepEqual(calls, want) {
+		t.Errorf("GetNowPlaying calls = %q, want %q", calls, want)
+	}
+}
+
+func TestMprisCurrentPlaylistTracks(t *testing.T) {
+	player := startFakeMprisPlayer(t, "tracklist")
+	a := newTestMprisBridge(t, "tracklist")
+
+	tracks, err := a.GetCurrentPlaylistTracks(context.Background(), 1, 5)
+	if err != nil {
+		t.Fatalf("GetCurrentPlaylistTracks: %v", err)
+	}
+	names := []string{}
+	for _, track := range tracks {
+		names = append(names, track.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"Two", "Three"}) {
+		t.Errorf("GetCurrentPlaylistTracks = %v, want [Two Three]", names)
+	}
+
+	if _, err := a.GetTrackById(context.Background(), "/track/9"); !errors.Is(err, ErrTrackNotFound) {
+		t.Errorf("GetTrackById of a missing track = %v, want ErrTrackNotFound", err)
+	}
+	if msg := a.PlayTrackInPlaylist(context.Background(), "", "/track/3")(); msg != (constant.EventTrackChanged{}) {
+		t.Errorf("PlayTrackInPlaylist = %v", msg)
+	}
+	if calls := player.takeCalls(); calls[len(calls)-1] != "GoTo /track/3" {
+		t.Errorf("PlayTrackInPlaylist calls = %q", calls)
+	}
+}
+
+func TestMprisControls(t *testing.T) {
+	player := startFakeMprisPlayer(t, "controls")
+	a := newTestMprisBridge(t, "controls")
+	ctx := context.Background()
+
+	for _, cmd := range []struct {
+		name string
+		msg  interface{}
+	}{
+		{"PlayPause", a.PlayPause(ctx)()},
+		{"SetVolume", a.SetVolume(ctx, 30)()},
+		{"SetRepeat", a.SetRepeat(ctx, model.RepeatOne)()},
+		{"SetShuffle", a.SetShuffle(ctx, true)()},
+		{"SetPlayerPosition", a.SetPlayerPosition(ctx, 42)()},
+		{"Seek", a.Seek(ctx, -10)()},
+	} {
+		if err, ok := cmd.msg.(error); ok {
+			t.Errorf("%s: %v", cmd.name, err)
+		}
+	}
+
+	if volume := player.prop(mprisPlayerIface, "Volume"); volume != 0.3 {
+		t.Errorf("Volume = %v, want 0.3", volume)
+	}
+	if loop := player.prop(mprisPlayerIface, "LoopStatus"); loop != "Track" {
+		t.Errorf("LoopStatus = %v, want Track", loop)
+	}
+	if shuffle := player.prop(mprisPlayerIface, "Shuffle"); shuffle != true {
+		t.Errorf("Shuffle = %v, want true", shuffle)
+	}
+	if position := player.prop(mprisPlayerIface, "Position"); position != int64(2e6) {
+		t.Errorf("Position = %v, want 2s after seeking 10s back from 12s", position)
+	}
+	calls := strings.Join(player.takeCalls(), "\n")
+	for _, want := range []string{"PlayPause", "SetPosition /track/2 42000000", "Seek -10000000"} {
+		if !strings.Contains(calls, want) {
+			t.Errorf("calls do not hold %q:\n%s", want, calls)
+		}
+	}
+
+	if err, _ := a.FavoriteCurrentTrack(ctx)().(error); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("FavoriteCurrentTrack = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestMprisNotRunning(t *testing.T) {
+	a := newTestMprisBridge(t, "missing")
+
+	got, err := a.GetNowPlaying(context.Background())
+	if err != nil || got.State != model.PlayerNotRunning {
+		t.Errorf("GetNowPlaying = %q, %v, want not running without error", got.State, err)
+	}
+	if err, _ := a.PlayPause(context.Background())().(error); !errors.Is(err, ErrAppNotRunning) {
+		t.Errorf("PlayPause = %v, want ErrAppNotRunning", err)
+	}
+}
+
+// a player started again get another instance name, the bridge find it without restarting
+func TestMprisPlayerRestarted(t *testing.T) {
+	first := startFakeMprisPlayer(t, "restart.instance1")
+	a := newTestMprisBridge(t, "restart")
+	if state, err := a.GetPlayerState(context.Background()); err != nil || state != model.PlayerPlaying {
+		t.Fatalf("GetPlayerState = %q, %v", state, err)
+	}
+
+	first.conn.Close()
+	second := startFakeMprisPlayer(t, "restart.instance2")
+	second.setProp(mprisPlayerIface, "PlaybackStatus", dbus.MakeVariant("Paused"))
+	if state, err := a.GetPlayerState(context.Background()); err != nil || state != model.PlayerPaused {
+		t.Errorf("GetPlayerState after restart = %q, %v, want paused", state, err)
+	}
+}
+
+// the art urls of the tracks not seen for long are dropped, the current track is seen again on every poll
+func TestMprisArtUrlsBounded(t *testing.T) {
+	a := NewMprisBridge(io.Discard, nil, "", nil).(*mprisBridge)
+	for i := 0; i < mprisArtUrlsSize*3; i++ {
+		a.rememberArtUrl("current", "file:///current.png")
+		a.rememberArtUrl(fmt.Sprintf("T%d", i), fmt.Sprintf("file:///%d.png", i))
+	}
+	if len(a.artUrls) != mprisArtUrlsSize || len(a.artUrlOrder) != mprisArtUrlsSize {
+		t.Fatalf("%d art urls, %d in order, want %d", len(a.artUrls), len(a.artUrlOrder), mprisArtUrlsSize)
+	}
+	if got := a.artUrls["current"]; got != "file:///current.png" {
+		t.Errorf("art url of the current track = %q", got)
+	}
+	if _, ok := a.artUrls["T0"]; ok {
+		t.Error("the art url of the first track is kept")
+	}
+	last := fmt.Sprintf("T%d", mprisArtUrlsSize*3-1)
+	if got := a.artUrls[last]; got == "" {
+		t.Errorf("the art url of the last track seen is dropped")
+	}
+}
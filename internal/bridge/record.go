@@ -0,0 +1,295 @@
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Interaction is one script run, a fixture file hold one interaction per line as JSON.
+type Interaction struct {
+	Lang     ScriptLang `json:"lang"`
+	Source   string     `json:"source"`
+	Args     []string   `json:"args"`
+	Stdout   string     `json:"stdout"`
+	Stderr   string     `json:"stderr"`
+	ExitCode int        `json:"exitCode"`
+	Output   []byte     `json:"output,omitempty"` // the file written by the script, see Script.Output
+	Err      string     `json:"err,omitempty"`    // set when the script did not end by itself, like a timeout
+	Timeout  bool       `json:"timeout,omitempty"`
+	Latency  float64    `json:"latency"` // seconds
+}
+
+// key tell which recordings answer a script
+func (i Interaction) key() string {
+	return string(i.Lang) + "\x00" + i.Source + "\x00" + strings.Join(i.Args, "\x00")
+}
+
+// outputArg stand for Script.Output in the recorded args, the path is a new temp file on every run
+const outputArg = "$OUTPUT"
+
+// recordedArgs return the args of script as they are recorded
+func recordedArgs(script Script) []string {
+	args := append([]string(nil), script.Args...)
+	for n, arg := range args {
+		if script.Output != "" && arg == script.Output {
+			args[n] = outputArg
+		}
+	}
+	return args
+}
+
+// ======= record
+
+// recordingRunner pass scripts to runner and write every interaction to w.
+type recordingRunner struct {
+	runner ScriptRunner
+
+	mu       sync.Mutex
+	w        io.Writer
+	redactor *redactor // nil when the names are recorded as they are
+	since    func(time.Time) time.Duration
+}
+
+// NewRecordingRunner wrap runner, every script and what it answered is appended to w.
+// With redact the names of tracks, albums, artists and playlists are replaced by placeholders.
+func NewRecordingRunner(runner ScriptRunner, w io.Writer, redact bool) ScriptRunner {
+	r := &recordingRunner{runner: runner, w: w, since: time.Since}
+	if redact {
+		r.redactor = newRedactor()
+	}
+	return r
+}
+
+func (r *recordingRunner) Run(ctx context.Context, script Script) (ScriptResult, error) {
+	start := time.Now()
+	result, err := r.runner.Run(ctx, script)
+	interaction := Interaction{
+		Lang:     script.Lang,
+		Source:   script.Source,
+		Args:     recordedArgs(script),
+		Stdout:   result.Stdout,
+		Stderr:   result.Stderr,
+		ExitCode: result.ExitCode,
+		Latency:  r.since(start).Seconds(),
+	}
+	var exitErr *ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		interaction.Err = err.Error()
+		interaction.Timeout = errors.Is(err, ErrTimeout)
+	}
+	var recordErr error
+	if script.Output != "" && err == nil {
+		interaction.Output, recordErr = os.ReadFile(script.Output)
+	}
+
+	r.mu.Lock()
+	if r.redactor != nil {
+		r.redactor.redact(&interaction, script.Names)
+	}
+	line, jsonErr := json.Marshal(interaction)
+	if jsonErr == nil {
+		_, jsonErr = r.w.Write(append(line, '\n'))
+	}
+	r.mu.Unlock()
+	if recordErr = errors.Join(recordErr, jsonErr); recordErr != nil {
+		// losing a recording should not break the player
+		fmt.Fprintln(os.Stderr, "error recording script:", recordErr)
+	}
+	return result, err
+}
+
+// ======= redact
+
+// redactedKeys are the JSON keys holding names, with the placeholder replacing them
+var redactedKeys = map[string]string{
+	"name":        "Name",
+	"album":       "Album",
+	"artist":      "Artist",
+	"albumArtist": "Artist",
+}
+
+// redactor replace names by placeholders. A name always get the same placeholder, so a redacted
+// recording still replay: the TUI send back the names it was answered, like a playlist to play.
+type redactor struct {
+	names  map[string]string
+	counts map[string]int
+}
+
+func newRedactor() *redactor {
+	return &redactor{names: map[string]string{}, counts: map[string]int{}}
+}
+
+func (r *redactor) placeholder(kind, name string) string {
+	if p, ok := r.names[name]; ok {
+		return p
+	}
+	r.counts[kind]++
+	p := fmt.Sprintf("%s %d", kind, r.counts[kind])
+	r.names[name] = p
+	return p
+}
+
+// redactJSON replace the names inside a decoded JSON document
+func (r *redactor) redactJSON(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		// in the order of the keys, so a recording is always redacted the same way
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if name, ok := v[key].(string); ok && name != "" && redactedKeys[key] != "" {
+				v[key] = r.placeholder(redactedKeys[key], name)
+				continue
+			}
+			r.redactJSON(v[key])
+		}
+	case []any:
+		for _, value := range v {
+			r.redactJSON(value)
+		}
+	}
+}
+
+// redact replace the names answered on stdout and the args at the indexes of names,
+// then the names seen so far in the error messages
+func (r *redactor) redact(i *Interaction, names []int) {
+	var v any
+	d := json.NewDecoder(strings.NewReader(i.Stdout))
+	d.UseNumber()
+	if d.Decode(&v) == nil {
+		switch v.(type) {
+		case map[string]any, []any:
+			r.redactJSON(v)
+			if stdout, err := json.Marshal(v); err == nil {
+				i.Stdout = string(stdout)
+			}
+		}
+	}
+
+	for _, n := range names {
+		if n < len(i.Args) && i.Args[n] != "" {
+			i.Args[n] = r.placeholder("Name", i.Args[n])
+		}
+	}
+
+	// errors quote the name they are about, like: Can't get playlist "Mix".
+	// Only quoted names are replaced, a short name is part of many words.
+	quoted := make([]string, 0, len(r.names))
+	for name := range r.names {
+		quoted = append(quoted, name)
+	}
+	// the longest first, so a name is not replaced inside a longer one
+	sort.Slice(quoted, func(a, b int) bool { return len(quoted[a]) > len(quoted[b]) })
+	pairs := make([]string, 0, 2*len(quoted))
+	for _, name := range quoted {
+		pairs = append(pairs, `"`+name+`"`, `"`+r.names[name]+`"`)
+	}
+	replacer := strings.NewReplacer(pairs...)
+	i.Stderr = replacer.Replace(i.Stderr)
+	i.Err = replacer.Replace(i.Err)
+}
+
+// ======= replay
+
+// ReplayRunner answer scripts with recorded interactions, so a recording can be played back without Music.
+// The recordings of the same script are answered in the order they were recorded, the last one
+// is repeated once they are used up, like the now-playing script polled on every tick.
+// Latency is not replayed, answers are immediate.
+type ReplayRunner struct {
+	mu      sync.Mutex
+	answers map[string][]Interaction
+	calls   []Script
+}
+
+// NewReplayRunner answer scripts with interactions.
+func NewReplayRunner(interactions []Interaction) *ReplayRunner {
+	r := &ReplayRunner{answers: map[string][]Interaction{}}
+	for _, i := range interactions {
+		r.answers[i.key()] = append(r.answers[i.key()], i)
+	}
+	return r
+}
+
+// LoadReplayRunner read a fixture written by the recording runner.
+func LoadReplayRunner(path string) (*ReplayRunner, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening recording: %w", err)
+	}
+	defer f.Close()
+
+	interactions := []Interaction{}
+	scanner := bufio.NewScanner(f)
+	// a script answering a whole playlist is a long line
+	scanner.Buffer(nil, 64<<20)
+	for n := 1; scanner.Scan(); n++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var i Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &i); err != nil {
+			return nil, fmt.Errorf("error reading recording %s line %d: %w", path, n, err)
+		}
+		interactions = append(interactions, i)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading recording %s: %w", path, err)
+	}
+	return NewReplayRunner(interactions), nil
+}
+
+// Calls return every script the runner received.
+func (r *ReplayRunner) Calls() []Script {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Script(nil), r.calls...)
+}
+
+func (r *ReplayRunner) Run(ctx context.Context, script Script) (ScriptResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, script)
+
+	if err := ctx.Err(); err != nil {
+		return ScriptResult{}, contextError(err)
+	}
+
+	key := Interaction{Lang: script.Lang, Source: script.Source, Args: recordedArgs(script)}.key()
+	answers := r.answers[key]
+	if len(answers) == 0 {
+		return ScriptResult{}, fmt.Errorf("replay script runner: no recording for script: %s", strings.TrimSpace(script.Source))
+	}
+	i := answers[0]
+	if len(answers) > 1 {
+		r.answers[key] = answers[1:]
+	}
+
+	result := ScriptResult{Stdout: i.Stdout, Stderr: i.Stderr, ExitCode: i.ExitCode}
+	if script.Output != "" && i.Output != nil {
+		if err := os.WriteFile(script.Output, i.Output, 0o644); err != nil {
+			return ScriptResult{}, fmt.Errorf("replay script runner: %w", err)
+		}
+	}
+	switch {
+	case i.Timeout:
+		// i.Err is the message of ErrTimeout itself, wrapping it would repeat it
+		return result, ErrTimeout
+	case i.Err != "":
+		return result, errors.New(i.Err)
+	case i.ExitCode != 0:
+		return result, &ExitError{ExitCode: i.ExitCode, Stderr: i.Stderr}
+	}
+	return result, nil
+}
@@ -0,0 +1,178 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"limiu82214/lazyAppleMusic/internal/artwork"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// go test ./internal/bridge -run TestReplayFixture -update record testdata/replay.jsonl again,
+// needed whenever a script it hold change
+var update = flag.Bool("update", false, "record the replay fixture again")
+
+const replayFixture = "testdata/replay.jsonl"
+
+// testArtwork is the cover written by the artwork script
+const testArtwork = "\x89PNG\r\n\x1a\n cover"
+
+// artworkRunner write testArtwork into the output of the scripts, like the artwork script of Music
+type artworkRunner struct {
+	ScriptRunner
+}
+
+func (r artworkRunner) Run(ctx context.Context, script Script) (ScriptResult, error) {
+	result, err := r.ScriptRunner.Run(ctx, script)
+	if err == nil && script.Output != "" {
+		err = os.WriteFile(script.Output, []byte(testArtwork), 0o644)
+	}
+	return result, err
+}
+
+func newTestArtworkStore(t *testing.T) *artwork.Store {
+	t.Helper()
+	store, err := artwork.NewStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// replaySession is what the fixture recorded: the player polled, the playlist played again, its cover and first tracks
+func replaySession(t *testing.T, a PlayerBridge) (model.NowPlaying, []model.Track, string) {
+	t.Helper()
+	ctx := context.Background()
+	nowPlaying, err := a.GetNowPlaying(ctx)
+	if err != nil {
+		t.Fatalf("GetNowPlaying: %v", err)
+	}
+	if msg := a.PlayPlaylist(ctx, nowPlaying.Playlist.Name)(); msg != nil {
+		t.Fatalf("PlayPlaylist: %v", msg)
+	}
+	path, err := a.GetArtwork(ctx, nowPlaying.Track)
+	if err != nil {
+		t.Fatalf("GetArtwork: %v", err)
+	}
+	tracks, err := a.GetCurrentPlaylistTracks(ctx, 0, 2)
+	if err != nil {
+		t.Fatalf("GetCurrentPlaylistTracks: %v", err)
+	}
+	return nowPlaying, tracks, path
+}
+
+func recordReplayFixture(t *testing.T) {
+	t.Helper()
+	fake := NewFakeScriptRunner().
+		OnStdout(`{"state":"playing","volume":40,"shuffle":false,"repeat":"off","position":12.5,
+			"track":{"id":"T1","name":"Secret Song","artist":"Secret Band","album":"Secret Album","duration":200},
+			"playlist":{"id":"P1","name":"Secret Mix","trackCount":2,"stamp":"2:400:2048"}}`, "snapshot.playlist").
+		OnStdout("", "play playlist").
+		OnStdout("OK", "artworks of aTrack").
+		OnStdout(`[{"id":"T1","name":"Secret Song","artist":"Secret Band","album":"Secret Album","duration":200},
+			{"id":"T2","name":"Other Song","artist":"Secret Band","duration":200}]`, "set p to current playlist")
+
+	f, err := os.Create(replayFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	recorder := NewRecordingRunner(artworkRunner{fake}, f, true)
+	// no latency, so recording again only change what the scripts changed
+	recorder.(*recordingRunner).since = func(time.Time) time.Duration { return 0 }
+	replaySession(t, NewAppleMusicBridge(io.Discard, recorder, newTestArtworkStore(t)))
+}
+
+func TestReplayFixture(t *testing.T) {
+	if *update {
+		recordReplayFixture(t)
+	}
+
+	recording, err := os.ReadFile(replayFixture)
+	if err != nil {
+		t.Fatalf("reading the fixture, run with -update to record it: %v", err)
+	}
+	if strings.Contains(string(recording), "Secret") {
+		t.Errorf("%s hold a name which should be redacted", replayFixture)
+	}
+
+	runner, err := LoadReplayRunner(replayFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nowPlaying, tracks, path := replaySession(t, NewAppleMusicBridge(io.Discard, runner, newTestArtworkStore(t)))
+
+	want := model.NowPlaying{
+		Track:    model.Track{Id: "T1", Name: "Name 2", Artist: "Artist 1", Album: "Album 1", Duration: 200},
+		State:    model.PlayerPlaying,
+		Position: 12.5,
+		Volume:   40,
+		Repeat:   model.RepeatOff,
+		Playlist: model.Playlist{Id: "P1", Name: "Name 1", TrackCount: 2, Stamp: "2:400:2048"},
+	}
+	if !reflect.DeepEqual(nowPlaying, want) {
+		t.Errorf("GetNowPlaying\n got %+v\nwant %+v", nowPlaying, want)
+	}
+	if len(tracks) != 2 || tracks[0].Name != "Name 2" || tracks[1].Name != "Name 3" || tracks[1].Artist != "Artist 1" {
+		t.Errorf("GetCurrentPlaylistTracks = %+v, want the redacted names of the snapshot", tracks)
+	}
+	// the cover is replayed into the new temp file of the store
+	if data, err := os.ReadFile(path); err != nil || string(data) != testArtwork {
+		t.Errorf("artwork = %q, %v, want %q", data, err, testArtwork)
+	}
+}
+
+func TestRedactorReplaceQuotedNames(t *testing.T) {
+	r := newRedactor()
+	i := Interaction{
+		Args:   []string{"Mix", "T1"},
+		Stdout: `{"name":"Mix","tracks":[{"id":"T1","name":"A","artist":"","lyrics":"A Mix"}]}`,
+		Stderr: `execution error: Can't get playlist "Mix". (-1728)`,
+	}
+	r.redact(&i, []int{0})
+
+	want := Interaction{
+		Args:   []string{"Name 1", "T1"},
+		Stdout: `{"name":"Name 1","tracks":[{"artist":"","id":"T1","lyrics":"A Mix","name":"Name 2"}]}`,
+		Stderr: `execution error: Can't get playlist "Name 1". (-1728)`,
+	}
+	if !reflect.DeepEqual(i, want) {
+		t.Errorf("redact\n got %+v\nwant %+v", i, want)
+	}
+}
+
+// names typed in are redacted too, though no answer told them before
+func TestRecordingRedactTypedNames(t *testing.T) {
+	var recording strings.Builder
+	runner := NewRecordingRunner(NewFakeScriptRunner().OnStdout("P9", "make new user playlist").OnStdout("[]", "search library"), &recording, true)
+	a := newTestAppleMusicBridge(runner)
+	ctx := context.Background()
+	a.CreatePlaylist(ctx, "Secret Mix")()
+	a.RenamePlaylist(ctx, "P9", "Secret Mix 2")()
+	a.SearchLibrary(ctx, "Secret Band", model.SearchSongs)
+
+	if strings.Contains(recording.String(), "Secret") {
+		t.Errorf("the recording hold a typed name:\n%s", recording.String())
+	}
+	// ids and options are kept, so the recording still replay
+	for _, arg := range []string{`["Name 1"]`, `["P9","Name 2"]`, `["Name 3","songs","200"]`} {
+		if !strings.Contains(recording.String(), `"args":`+arg) {
+			t.Errorf("the recording miss the args %s", arg)
+		}
+	}
+}
+
+func TestReplayTimeout(t *testing.T) {
+	script := Script{Lang: AppleScript, Source: "return 1"}
+	runner := NewReplayRunner([]Interaction{{Lang: AppleScript, Source: "return 1", Err: ErrTimeout.Error(), Timeout: true}})
+	_, err := runner.Run(context.Background(), script)
+	if !errors.Is(err, ErrTimeout) || err.Error() != ErrTimeout.Error() {
+		t.Errorf("Run = %v, want %v", err, ErrTimeout)
+	}
+}
@@ -0,0 +1,173 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScriptRunner execute a script and report what it printed.
+// A non-zero exit code is returned as *ExitError together with the result,
+// a script stopped by the deadline of ctx return ErrTimeout.
+type ScriptRunner interface {
+	Run(ctx context.Context, script Script) (ScriptResult, error)
+}
+
+type ScriptLang string
+
+const (
+	AppleScript ScriptLang = "AppleScript"
+	JavaScript  ScriptLang = "JavaScript" // JavaScript for Automation (JXA)
+)
+
+type Script struct {
+	Lang   ScriptLang
+	Source string
+	Args   []string // passed to the run handler as argv
+	Output string   // path of a file the script write, one of Args. Recordings keep what was written instead of the path
+	Names  []int    // indexes of the Args holding names, like a playlist to create, redacted in recordings
+}
+
+type ScriptResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+type ExitError struct {
+	ExitCode int
+	Stderr   string
+}
+
+func (e *ExitError) Error() string {
+	if msg := strings.TrimSpace(e.Stderr); msg != "" {
+		return fmt.Sprintf("exit status %d: %s", e.ExitCode, msg)
+	}
+	return fmt.Sprintf("exit status %d", e.ExitCode)
+}
+
+// ======= osascript
+
+type osascriptRunner struct{}
+
+func NewOsascriptRunner() ScriptRunner {
+	return osascriptRunner{}
+}
+
+func (r osascriptRunner) Run(ctx context.Context, script Script) (ScriptResult, error) {
+	lang := script.Lang
+	if lang == "" {
+		lang = AppleScript
+	}
+
+	var stdout, stderr bytes.Buffer
+	// "--" stop option parsing, so an argument like "-e" stay an argument
+	args := append([]string{"-l", string(lang), "-e", script.Source, "--"}, script.Args...)
+	cmd := exec.CommandContext(ctx, "osascript", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// once killed by ctx, do not wait forever on output held open by a child of osascript
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	result := ScriptResult{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		ExitCode: cmd.ProcessState.ExitCode(),
+	}
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return result, contextError(ctxErr)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return result, &ExitError{ExitCode: exitErr.ExitCode(), Stderr: result.Stderr}
+		}
+		return result, err
+	}
+	return result, nil
+}
+
+// contextError map the error of a done context as the runners report it: ErrTimeout past the deadline,
+// the cancellation as is
+func contextError(ctxErr error) error {
+	if errors.Is(ctxErr, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrTimeout, ctxErr)
+	}
+	return ctxErr
+}
+
+// ======= fake
+
+// FakeScriptRunner answer scripts with canned responses, so the bridge can run without osascript.
+// Rules are checked in the order they were added, the first rule whose every pattern
+// is contained in the script source wins.
+type FakeScriptRunner struct {
+	mu    sync.Mutex
+	rules []fakeScriptRule
+	calls []Script
+}
+
+type fakeScriptRule struct {
+	patterns []string
+	result   ScriptResult
+}
+
+func NewFakeScriptRunner() *FakeScriptRunner {
+	return &FakeScriptRunner{}
+}
+
+// On register a response for scripts containing all the patterns.
+func (f *FakeScriptRunner) On(result ScriptResult, patterns ...string) *FakeScriptRunner {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, fakeScriptRule{patterns: patterns, result: result})
+	return f
+}
+
+// OnStdout is a shortcut of On for a successful script.
+func (f *FakeScriptRunner) OnStdout(stdout string, patterns ...string) *FakeScriptRunner {
+	return f.On(ScriptResult{Stdout: stdout}, patterns...)
+}
+
+// Calls return every script the runner received.
+func (f *FakeScriptRunner) Calls() []Script {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Script(nil), f.calls...)
+}
+
+func (f *FakeScriptRunner) Run(ctx context.Context, script Script) (ScriptResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, script)
+
+	if err := ctx.Err(); err != nil {
+		return ScriptResult{}, contextError(err)
+	}
+
+	for _, rule := range f.rules {
+		if !matchAll(script.Source, rule.patterns) {
+			continue
+		}
+		if rule.result.ExitCode != 0 {
+			return rule.result, &ExitError{ExitCode: rule.result.ExitCode, Stderr: rule.result.Stderr}
+		}
+		return rule.result, nil
+	}
+	return ScriptResult{}, fmt.Errorf("fake script runner: no response for script: %s", strings.TrimSpace(script.Source))
+}
+
+func matchAll(script string, patterns []string) bool {
+	for _, p := range patterns {
+		if !strings.Contains(script, p) {
+			return false
+		}
+	}
+	return true
+}
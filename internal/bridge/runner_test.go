@@ -0,0 +1,69 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"io"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"reflect"
+	"testing"
+)
+
+func TestFakeScriptRunnerRules(t *testing.T) {
+	runner := NewFakeScriptRunner().
+		OnStdout("first", "play", "playlist").
+		OnStdout("second", "play").
+		On(ScriptResult{ExitCode: 1, Stderr: "execution error"}, "pause")
+	ctx := context.Background()
+
+	tests := []struct {
+		script     string
+		wantStdout string
+		wantExit   int
+	}{
+		{`tell application "Music" to play playlist "Mix"`, "first", 0},
+		{`tell application "Music" to play`, "second", 0},
+		{`tell application "Music" to pause`, "", 1},
+	}
+	for _, tt := range tests {
+		result, err := runner.Run(ctx, Script{Lang: AppleScript, Source: tt.script})
+		if result.Stdout != tt.wantStdout {
+			t.Errorf("Run(%q) stdout = %q, want %q", tt.script, result.Stdout, tt.wantStdout)
+		}
+		var exitErr *ExitError
+		if tt.wantExit == 0 && err != nil {
+			t.Errorf("Run(%q) = %v, want no error", tt.script, err)
+		}
+		if tt.wantExit != 0 && (!errors.As(err, &exitErr) || exitErr.ExitCode != tt.wantExit || exitErr.Stderr != "execution error") {
+			t.Errorf("Run(%q) = %v, want exit status %d", tt.script, err, tt.wantExit)
+		}
+	}
+
+	if _, err := runner.Run(ctx, Script{Lang: AppleScript, Source: "stop"}); err == nil {
+		t.Error("Run without matching rule succeeded")
+	}
+	if calls := runner.Calls(); len(calls) != len(tests)+1 || calls[0].Source != tests[0].script {
+		t.Errorf("Calls = %q", calls)
+	}
+}
+
+func TestGetCurrentTrackThroughRunner(t *testing.T) {
+	runner := NewFakeScriptRunner().OnStdout(
+		`{"id":"T1","name":"Song","time":"3:20","duration":200.5,"playedCount":4,"favorited":true,"album":"Album","artist":"Band"}`+"\n",
+		"currentTrack.properties")
+	track, err := NewAppleMusicBridge(io.Discard, runner, nil).GetCurrentTrack(context.Background())
+	if err != nil {
+		t.Fatalf("GetCurrentTrack: %v", err)
+	}
+	want := model.Track{Id: "T1", Name: "Song", Time: "3:20", Duration: 200.5, PlayedCount: 4, Favorited: true, Album: "Album", Artist: "Band"}
+	if !reflect.DeepEqual(track, want) {
+		t.Errorf("GetCurrentTrack\n got %+v\nwant %+v", track, want)
+	}
+
+	// Music not running
+	runner = NewFakeScriptRunner().OnStdout("null\n", "currentTrack.properties")
+	track, err = NewAppleMusicBridge(io.Discard, runner, nil).GetCurrentTrack(context.Background())
+	if err != nil || track.Name != "No Track Playing" {
+		t.Errorf("GetCurrentTrack not running = %+v, %v", track, err)
+	}
+}
@@ -0,0 +1,129 @@
+package bridge
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// scriptBuilder is the only place where script sources are assembled.
+// Values never get formatted into a source as raw text: the app name is written as an
+// escaped literal, everything else (names, ids, paths, numbers) is passed through argv.
+type scriptBuilder struct {
+	appName string
+}
+
+// AppleScript wrap body into an `on run argv` handler, followed by the handlers of appleScriptHandlers.
+// "$APP" is replaced by the quoted app name, so it can be used in `tell application $APP`.
+// Arguments are read with `item n of argv`.
+func (b scriptBuilder) AppleScript(body string, args ...string) Script {
+	source := "on run argv\n" + body + "\nend run\n" + appleScriptHandlers
+	source = strings.ReplaceAll(source, "$APP", QuoteAppleScript(b.appName))
+	return Script{Lang: AppleScript, Source: source, Args: args}
+}
+
+// appleScriptHandlers is appended to every AppleScript, inside a tell block call them with `my`.
+// AppleScript has no JSON encoder, jsonString is enough to return a JSON document built by hand.
+// trackNotFound and playlistNotFound raise the error numbers errorNumbers map onto ErrTrackNotFound and ErrPlaylistNotFound,
+// the find handlers raise them when nothing has the persistent ID. playlistChanged raise ErrPlaylistChanged.
+const appleScriptHandlers = `
+on replaceText(theText, searchString, replacementString)
+	set AppleScript's text item delimiters to searchString
+	set theItems to every text item of theText
+	set AppleScript's text item delimiters to replacementString
+	set theText to theItems as text
+	set AppleScript's text item delimiters to ""
+	return theText
+end replaceText
+
+on jsonString(s)
+	if s is missing value then return "null"
+	set s to s as text
+	set s to replaceText(s, "\\", "\\\\")
+	set s to replaceText(s, "\"", "\\\"")
+	set s to replaceText(s, return, "\\r")
+	set s to replaceText(s, linefeed, "\\n")
+	set s to replaceText(s, tab, "\\t")
+	-- the other control characters are invalid in JSON too, one split tell whether s hold any
+	set controls to {}
+	repeat with n from 1 to 31
+		set end of controls to character id n
+	end repeat
+	set AppleScript's text item delimiters to controls
+	set hasControls to (count of text items of s) > 1
+	set AppleScript's text item delimiters to ""
+	if hasControls then
+		set hexDigits to "0123456789abcdef"
+		repeat with n from 1 to 31
+			set s to replaceText(s, character id n, "\\u00" & character (n div 16 + 1) of hexDigits & character (n mod 16 + 1) of hexDigits)
+		end repeat
+	end if
+	return "\"" & s & "\""
+end jsonString
+
+on trackNotFound(trackID)
+	error "track not found: " & trackID number 1001
+end trackNotFound
+
+on playlistNotFound(playlistID)
+	error "playlist not found: " & playlistID number 1002
+end playlistNotFound
+
+on playlistChanged(playlistID)
+	error "playlist changed: " & playlistID number 1003
+end playlistChanged
+
+on findLibraryTrack(trackID)
+	tell application $APP
+		try
+			return first track of library playlist 1 whose persistent ID is trackID
+		end try
+	end tell
+	trackNotFound(trackID)
+end findLibraryTrack
+
+on findUserPlaylist(playlistID)
+	tell application $APP
+		try
+			return first user playlist whose persistent ID is playlistID
+		end try
+	end tell
+	playlistNotFound(playlistID)
+end findUserPlaylist
+
+on joinText(theList, separator)
+	set AppleScript's text item delimiters to separator
+	set theText to theList as text
+	set AppleScript's text item delimiters to ""
+	return theText
+end joinText
+`
+
+// JXA wrap body into a `function run(argv)`, with `app` bound to the application.
+// Arguments are read with `argv[n]`.
+func (b scriptBuilder) JXA(body string, args ...string) Script {
+	source := jxaPrelude +
+		"function run(argv) {\n" +
+		"const app = Application(" + QuoteJS(b.appName) + ");\n" +
+		body +
+		"\n}\n"
+	return Script{Lang: JavaScript, Source: source, Args: args}
+}
+
+// QuoteAppleScript return s as an AppleScript string literal.
+func QuoteAppleScript(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"\n", `\n`,
+		"\r", `\r`,
+		"\t", `\t`,
+	)
+	return `"` + r.Replace(s) + `"`
+}
+
+// QuoteJS return s as a JavaScript string literal.
+func QuoteJS(s string) string {
+	// json.Marshal escape quotes, control characters, <, > and U+2028/U+2029
+	data, _ := json.Marshal(s)
+	return string(data)
+}
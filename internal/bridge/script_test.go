@@ -0,0 +1,127 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// hostileNames are names a library can hold which break a script built by formatting them into its source
+var hostileNames = []string{
+	`My "Best" Mix`,
+	`back\slash`,
+	`trailing backslash\`,
+	"two\nlines",
+	"carriage\rreturn",
+	"tab\there",
+	"bell\a and unit separator\x1f",
+	"\x01\x02\x03",
+	`" & (do shell script "x") & "`,
+	`"); Application("Finder").delete(x); ("`,
+	`-e`,
+	`--`,
+	`${x}`,
+	"`x`",
+	`$(x)`,
+	`'single' quotes`,
+	`end tell`,
+	"\u2028line separator\u2029",
+	`<script>`,
+	`日本語のプレイリスト`,
+	``,
+}
+
+func TestBuilderPassNamesAsArgv(t *testing.T) {
+	ctx := context.Background()
+	for _, name := range hostileNames {
+		tests := []struct {
+			call func(a PlayerBridge)
+			want []string
+		}{
+			{func(a PlayerBridge) { a.PlayPlaylist(ctx, name)() }, []string{name}},
+			{func(a PlayerBridge) { a.CreatePlaylist(ctx, name)() }, []string{name}},
+			{func(a PlayerBridge) { a.RenamePlaylist(ctx, "P1", name)() }, []string{"P1", name}},
+			{func(a PlayerBridge) { a.PlayTrackById(ctx, name)() }, []string{name}},
+			{func(a PlayerBridge) { a.AddTracksToPlaylist(ctx, name, []string{name, "T2"})() }, []string{name, name, "T2"}},
+			{func(a PlayerBridge) { a.SearchLibrary(ctx, name+"x", model.SearchSongs) }, []string{name + "x", "songs", "200"}},
+			{func(a PlayerBridge) { a.GetTrackById(ctx, name) }, []string{name}},
+		}
+		for i, tt := range tests {
+			runner := NewFakeScriptRunner().OnStdout("[]")
+			tt.call(newTestAppleMusicBridge(runner))
+
+			calls := runner.Calls()
+			if len(calls) != 1 {
+				t.Fatalf("%q call %d: ran %d scripts, want 1", name, i, len(calls))
+			}
+			if !reflect.DeepEqual(calls[0].Args, tt.want) {
+				t.Errorf("%q call %d: argv = %q, want %q", name, i, calls[0].Args, tt.want)
+			}
+		}
+	}
+}
+
+func TestBuilderSourceDoNotDependOnArgs(t *testing.T) {
+	b := scriptBuilder{appName: "Music"}
+	for _, name := range hostileNames {
+		if got, want := b.AppleScript("return item 1 of argv", name).Source, b.AppleScript("return item 1 of argv").Source; got != want {
+			t.Errorf("%q changed the AppleScript source", name)
+		}
+		if got, want := b.JXA("return argv[0];", name).Source, b.JXA("return argv[0];").Source; got != want {
+			t.Errorf("%q changed the JXA source", name)
+		}
+	}
+}
+
+func TestQuoteJS(t *testing.T) {
+	for _, name := range hostileNames {
+		quoted := QuoteJS(name)
+		var back string
+		if err := json.Unmarshal([]byte(quoted), &back); err != nil || back != name {
+			t.Errorf("QuoteJS(%q) = %s, decode back to %q, %v", name, quoted, back, err)
+		}
+		// a raw line break end a JavaScript string literal
+		if strings.ContainsAny(quoted, "\n\r\u2028\u2029") {
+			t.Errorf("QuoteJS(%q) = %s hold a line terminator", name, quoted)
+		}
+	}
+}
+
+func TestQuoteAppleScript(t *testing.T) {
+	unquote := strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n", `\r`, "\r", `\t`, "\t")
+	for _, name := range hostileNames {
+		quoted := QuoteAppleScript(name)
+		if !strings.HasPrefix(quoted, `"`) || !strings.HasSuffix(quoted, `"`) || len(quoted) < 2 {
+			t.Fatalf("QuoteAppleScript(%q) = %s is not a string literal", name, quoted)
+		}
+		inner := quoted[1 : len(quoted)-1]
+		if back := unquote.Replace(inner); back != name {
+			t.Errorf("QuoteAppleScript(%q) = %s, unquote back to %q", name, quoted, back)
+		}
+		// every quote inside is escaped, so the literal can not end early
+		for i := 0; i < len(inner); i++ {
+			switch inner[i] {
+			case '\\':
+				i++
+			case '"':
+				t.Errorf("QuoteAppleScript(%q) = %s end the literal early", name, quoted)
+			case '\n', '\r':
+				t.Errorf("QuoteAppleScript(%q) = %s hold a raw line break", name, quoted)
+			}
+		}
+	}
+}
+
+// the app name is the only value written into a source, as a literal
+func TestBuilderQuoteAppName(t *testing.T) {
+	b := scriptBuilder{appName: `Mu"sic`}
+	if source := b.AppleScript("tell application $APP to play").Source; !strings.Contains(source, `tell application "Mu\"sic" to play`) {
+		t.Errorf("AppleScript source = %s", source)
+	}
+	if source := b.JXA("").Source; !strings.Contains(source, `Application("Mu\"sic")`) {
+		t.Errorf("JXA source = %s", source)
+	}
+}
@@ -1,6 +1,7 @@
 package constant
 
 import (
+	"limiu82214/lazyAppleMusic/internal/lyrics"
 	"limiu82214/lazyAppleMusic/internal/model"
 	"time"
 
@@ -8,6 +9,8 @@ import (
 )
 
 type TickMsg time.Time
+// LyricsTickMsg move the highlight of the synced lyrics between two seconds of the player
+type LyricsTickMsg time.Time
 
 type StyleMsg struct {
 	Style lipgloss.Style
@@ -17,22 +20,123 @@ type StyleMsg struct {
 // Event for ation already been
 
 type EventTrackChanged struct{}
-type EventUpdateTrackData model.Track
+type EventPlayerStateChanged struct{}
 type EventUpdateCurrentAlbumImg string
-type EventUpdatePlayerPosition int
-type EventUpdateCurrentPlaylist model.Playlist
+// EventUpdateArtwork carry the file of the current artwork, empty when the track has none
+type EventUpdateArtwork string
+// EventPlaylistPickerFailed tell the playlist picker could not load the playlists or add the tracks
+type EventPlaylistPickerFailed struct {
+	Err error
+}
+// EventPlayFailed tell a track asked by the user or by the queue could not be played
+type EventPlayFailed struct {
+	Err error
+}
+// EventArtworkFailed tell the current artwork could not be fetched, it is fetched again on the next tick
+type EventArtworkFailed struct {
+	Err error
+}
+type EventUpdateNowPlaying model.NowPlaying
+type EventPlayerPositionChanged int
+// EventUpdateCurrentPlaylist carry one page of the current playlist:
+// Playlist.Tracks start at Offset, Playlist.TrackCount is the size of the whole list.
+// Err is set when the page could not be fetched, the page shown is then kept.
+type EventUpdateCurrentPlaylist struct {
+	Playlist model.Playlist
+	Offset   int
+	Err      error
+}
 type EventFavoriteTrackId string
+type EventUpdatePlayerState model.PlayerState
+type EventUpdateShuffle bool
+type EventUpdateRepeat model.RepeatMode
+// EventPlaylistChanged carry the id of the playlist whose tracks changed
+type EventPlaylistChanged string
+// EventPlaylistsChanged is sent when a playlist is renamed or deleted
+type EventPlaylistsChanged struct{}
+type EventPlaylistCreated model.Playlist
+type EventUpdateUserPlaylists []model.Playlist
+type EventQueueChanged struct{}
+type EventTrackRatingChanged struct {
+	TrackId string
+	Rating  int // 0-100, 20 per star
+}
+// EventTrackDislikedChanged is sent once the track is disliked or not anymore, Music unfavorite a disliked track
+type EventTrackDislikedChanged struct {
+	TrackId  string
+	Disliked bool
+}
+// EventUpdateSearchResults carry the results of a library search, Err is set when the search failed
+type EventUpdateSearchResults struct {
+	Query  string
+	Field  model.SearchField
+	Tracks []model.Track
+	Err    error
+}
+// EventUpdateLyrics carry the lyrics of a track, Synced is the LRC file found for it, if any.
+// Err is set when the track could not be read
+type EventUpdateLyrics struct {
+	TrackId string
+	Lyrics  string
+	Synced  *lyrics.Lyrics
+	Source  string // file of Synced
+	Err     error
+}
+// EventUpdatePlayerPosition carry the position the playing view count, for the views following the track
+type EventUpdatePlayerPosition struct {
+	Position time.Duration
+	Playing  bool
+}
+// EventPlayerChanged is sent by a bridge watching the player, it carry what changed
+type EventPlayerChanged []string
 
 // Should for need to be some action
 
 type ShouldFavoriteTrackId string
 type ShouldUpdateTabs model.TabTuiData
 type ShouldPlayTrackId string
+// ShouldPlayTrackInPlaylist play the track without leaving the playlist
+type ShouldPlayTrackInPlaylist struct {
+	PlaylistId string
+	TrackId    string
+}
 type ShouldSelectTrackId string
+type ShouldRateTrack struct {
+	TrackId string
+	Rating  int
+}
+type ShouldSetTrackDisliked struct {
+	TrackId  string
+	Disliked bool
+}
+// ShouldQueueTrack put the track in the Up Next queue, at the front when Next
+type ShouldQueueTrack struct {
+	Track model.Track
+	Next  bool
+}
+// ShouldAddTracksToPlaylist open the playlist picker for the tracks
+type ShouldAddTracksToPlaylist []model.Track
+// ShouldRemoveTrackFromPlaylist ask before removing the track from the playlist
+type ShouldRemoveTrackFromPlaylist struct {
+	Playlist model.Playlist
+	Track    model.Track
+}
 type ShouldClearFilter struct{}
 
 
 const (
 	Favorite   = "󰋑"
 	Unfavorite = ""
+	ShuffleOn  = "󰒝"
+	ShuffleOff = "󰒞"
+	RepeatAll  = "󰑖"
+	RepeatOne  = "󰑘"
+	RepeatOff  = "󰑗"
+	Volume     = "󰕾"
+	Playing    = "󰐊"
+	Paused     = "󰏤"
+	Stopped    = "󰓛"
+	StarFull   = "󰓎"
+	StarEmpty  = "󰓒"
+	Disliked   = "󰔑"
 )
@@ -0,0 +1,316 @@
+// Package library read the library exported by Music or iTunes (File > Library > Export Library...),
+// so the library can be browsed without asking Music for every track.
+package library
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Library is a read-only snapshot of the exported library.
+// Tracks and playlists keep the persistent ids of Music, so they can be played through the live bridge.
+// It is safe for concurrent use.
+type Library struct {
+	mu        sync.RWMutex
+	tracks    map[string]model.Track
+	order     []string // track ids in the order of the export
+	playlists []playlist
+	root      int // index of the playlist holding the whole library, -1 when the export has none
+}
+
+type playlist struct {
+	model.Playlist
+	trackIds []string
+}
+
+// Load parse the Library.xml at path.
+func Load(path string) (*Library, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening library: %w", err)
+	}
+	defer f.Close()
+	lib, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing library %s: %w", path, err)
+	}
+	return lib, nil
+}
+
+// Parse read the XML property list of a library export.
+func Parse(r io.Reader) (*Library, error) {
+	value, err := decodePlist(r)
+	if err != nil {
+		return nil, err
+	}
+	export, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("not a library export, the top level is not a dict")
+	}
+	trackDicts, ok := export["Tracks"].(map[string]any)
+	if !ok {
+		return nil, errors.New("not a library export, no Tracks")
+	}
+
+	lib := &Library{tracks: map[string]model.Track{}, root: -1}
+	// playlists list their items by the Track ID of the export, not by the persistent id
+	byTrackId := map[int]string{}
+	for _, v := range trackDicts {
+		dict, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		track := parseTrack(dict)
+		if track.Id == "" {
+			continue
+		}
+		lib.tracks[track.Id] = track
+		byTrackId[dictInt(dict, "Track ID")] = track.Id
+	}
+	// the dict has no order, the Track ID follow the order tracks were added
+	trackIds := make([]int, 0, len(byTrackId))
+	for trackId := range byTrackId {
+		trackIds = append(trackIds, trackId)
+	}
+	sort.Ints(trackIds)
+	for _, trackId := range trackIds {
+		lib.order = append(lib.order, byTrackId[trackId])
+	}
+
+	playlistArray, _ := export["Playlists"].([]any)
+	for _, v := range playlistArray {
+		dict, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		p, ok := parsePlaylist(dict, byTrackId)
+		if !ok {
+			continue
+		}
+		if dictBool(dict, "Master") {
+			lib.root = len(lib.playlists)
+		}
+		lib.playlists = append(lib.playlists, p)
+	}
+	lib.fillFolders()
+	return lib, nil
+}
+
+func parseTrack(dict map[string]any) model.Track {
+	track := model.Track{
+		Id:          dictString(dict, "Persistent ID"),
+		Name:        dictString(dict, "Name"),
+		Duration:    float64(dictInt(dict, "Total Time")) / 1000,
+		PlayedCount: dictInt(dict, "Play Count"),
+		// iTunes and the first versions of Music call it loved
+		Favorited:   dictBool(dict, "Favorited") || dictBool(dict, "Loved"),
+		Disliked:    dictBool(dict, "Disliked"),
+		Artist:      dictString(dict, "Artist"),
+		Album:       dictString(dict, "Album"),
+		AlbumArtist: dictString(dict, "Album Artist"),
+		DateAdded:   dictTime(dict, "Date Added"),
+	}
+	// a computed rating is the rating of the album, the track itself is not rated
+	if !dictBool(dict, "Rating Computed") {
+		track.Rating = dictInt(dict, "Rating")
+	}
+	sec := int(track.Duration)
+	track.Time = fmt.Sprintf("%d:%02d", sec/60, sec%60)
+	return track
+}
+
+func parsePlaylist(dict map[string]any, byTrackId map[int]string) (playlist, bool) {
+	// hidden playlists are the internal ones of Music, like the genius playlists, but the library itself is hidden too
+	if visible, ok := dict["Visible"].(bool); ok && !visible && !dictBool(dict, "Master") {
+		return playlist{}, false
+	}
+	p := playlist{Playlist: model.Playlist{
+		Id:        dictString(dict, "Playlist Persistent ID"),
+		Name:      dictString(dict, "Name"),
+		Favorited: dictBool(dict, "Favorited") || dictBool(dict, "Loved"),
+		ParentId:  dictString(dict, "Parent Persistent ID"),
+		Folder:    dictBool(dict, "Folder"),
+	}}
+	if p.Id == "" {
+		return playlist{}, false
+	}
+	items, _ := dict["Playlist Items"].([]any)
+	for _, item := range items {
+		itemDict, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		if id, ok := byTrackId[dictInt(itemDict, "Track ID")]; ok {
+			p.trackIds = append(p.trackIds, id)
+		}
+	}
+	p.TrackCount = len(p.trackIds)
+	return p, true
+}
+
+// fillFolders give every folder the tracks of the playlists inside, as Music does, when the export did not list them
+func (lib *Library) fillFolders() {
+	var tracksOf func(id string, seen map[string]bool) []string
+	tracksOf = func(id string, seen map[string]bool) []string {
+		ids := []string{}
+		for _, p := range lib.playlists {
+			if p.ParentId != id || seen[p.Id] {
+				continue
+			}
+			seen[p.Id] = true
+			if p.Folder {
+				ids = append(ids, tracksOf(p.Id, seen)...)
+			} else {
+				ids = append(ids, p.trackIds...)
+			}
+		}
+		return ids
+	}
+	for i, p := range lib.playlists {
+		if p.Folder && len(p.trackIds) == 0 {
+			seen := map[string]bool{}
+			trackIds := []string{}
+			for _, id := range tracksOf(p.Id, map[string]bool{p.Id: true}) {
+				if !seen[id] {
+					seen[id] = true
+					trackIds = append(trackIds, id)
+				}
+			}
+			lib.playlists[i].trackIds = trackIds
+			lib.playlists[i].TrackCount = len(trackIds)
+		}
+	}
+}
+
+// ======= read
+
+// Track return the track with the persistent id.
+func (lib *Library) Track(id string) (model.Track, bool) {
+	lib.mu.RLock()
+	defer lib.mu.RUnlock()
+	track, ok := lib.tracks[id]
+	return track, ok
+}
+
+// Root return the playlist holding the whole library.
+func (lib *Library) Root() model.Playlist {
+	lib.mu.RLock()
+	defer lib.mu.RUnlock()
+	if lib.root >= 0 {
+		return lib.playlists[lib.root].Playlist
+	}
+	return model.Playlist{Id: rootId, Name: "Library", TrackCount: len(lib.order)}
+}
+
+// rootId is the id of the library playlist of an export without one
+const rootId = "library"
+
+// Playlists return the playlists and folders, without their tracks, in the order of the export.
+func (lib *Library) Playlists() []model.Playlist {
+	lib.mu.RLock()
+	defer lib.mu.RUnlock()
+	playlists := make([]model.Playlist, 0, len(lib.playlists))
+	for _, p := range lib.playlists {
+		playlists = append(playlists, p.Playlist)
+	}
+	return playlists
+}
+
+// Playlist return the playlist with the persistent id, without its tracks.
+func (lib *Library) Playlist(id string) (model.Playlist, bool) {
+	lib.mu.RLock()
+	defer lib.mu.RUnlock()
+	if p, ok := lib.playlist(id); ok {
+		return p.Playlist, true
+	}
+	return model.Playlist{}, false
+}
+
+// PlaylistTracks return up to limit tracks of the playlist starting at offset, ok is false when there is no such playlist.
+func (lib *Library) PlaylistTracks(id string, offset, limit int) ([]model.Track, bool) {
+	lib.mu.RLock()
+	defer lib.mu.RUnlock()
+	p, ok := lib.playlist(id)
+	if !ok {
+		return nil, false
+	}
+	tracks := []model.Track{}
+	if offset < 0 || offset >= len(p.trackIds) || limit <= 0 {
+		return tracks, true
+	}
+	for _, trackId := range p.trackIds[offset:min(offset+limit, len(p.trackIds))] {
+		tracks = append(tracks, lib.tracks[trackId])
+	}
+	return tracks, true
+}
+
+// playlist find a playlist, the caller should hold mu
+func (lib *Library) playlist(id string) (playlist, bool) {
+	if id == rootId && lib.root < 0 {
+		return playlist{Playlist: model.Playlist{Id: rootId, Name: "Library", TrackCount: len(lib.order)}, trackIds: lib.order}, true
+	}
+	for _, p := range lib.playlists {
+		if p.Id == id {
+			return p, true
+		}
+	}
+	return playlist{}, false
+}
+
+// Search return up to limit tracks whose field contain query, case insensitive, in the order of the export.
+func (lib *Library) Search(query string, field model.SearchField, limit int) []model.Track {
+	query = strings.ToLower(strings.TrimSpace(query))
+	tracks := []model.Track{}
+	if query == "" {
+		return tracks
+	}
+
+	lib.mu.RLock()
+	defer lib.mu.RUnlock()
+	for _, id := range lib.order {
+		if len(tracks) >= limit {
+			break
+		}
+		t := lib.tracks[id]
+		values := []string{}
+		switch field {
+		case model.SearchSongs:
+			values = append(values, t.Name)
+		case model.SearchArtists:
+			values = append(values, t.Artist, t.AlbumArtist)
+		case model.SearchAlbums:
+			values = append(values, t.Album)
+		default:
+			values = append(values, t.Name, t.Artist, t.AlbumArtist, t.Album)
+		}
+		for _, value := range values {
+			if strings.Contains(strings.ToLower(value), query) {
+				tracks = append(tracks, t)
+				break
+			}
+		}
+	}
+	return tracks
+}
+
+// ======= write
+
+// Update change the track in memory, so the snapshot follow the changes made through the live bridge.
+// The export itself is never written.
+func (lib *Library) Update(id string, update func(*model.Track)) bool {
+	lib.mu.Lock()
+	defer lib.mu.Unlock()
+	track, ok := lib.tracks[id]
+	if !ok {
+		return false
+	}
+	update(&track)
+	lib.tracks[id] = track
+	return true
+}
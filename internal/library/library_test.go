@@ -0,0 +1,171 @@
+package library
+
+import (
+	"limiu82214/lazyAppleMusic/internal/model"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func loadTestLibrary(t *testing.T) *Library {
+	t.Helper()
+	lib, err := Load("testdata/Library.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lib
+}
+
+// ids return the ids of the tracks, or of the playlists
+func ids[T model.Track | model.Playlist](items []T) []string {
+	ids := []string{}
+	for _, item := range items {
+		switch item := any(item).(type) {
+		case model.Track:
+			ids = append(ids, item.Id)
+		case model.Playlist:
+			ids = append(ids, item.Id)
+		}
+	}
+	return ids
+}
+
+func TestParseTracks(t *testing.T) {
+	lib := loadTestLibrary(t)
+
+	tests := []struct {
+		id   string
+		want model.Track
+	}{
+		{"T1", model.Track{
+			Id: "T1", Name: "First Song", Artist: "Band", AlbumArtist: "Band & Friends", Album: "Album",
+			Duration: 185, Time: "3:05", PlayedCount: 7, Favorited: true, // loved
+			DateAdded: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		}},
+		{"T2", model.Track{
+			Id: "T2", Name: "Second Song", Artist: "Band", Album: "Album",
+			Duration: 242, Time: "4:02", Rating: 80,
+		}},
+		{"T3", model.Track{
+			Id: "T3", Name: "Third & Last", Artist: "Other Band", Album: "Other",
+			Duration: 61.5, Time: "1:01", Disliked: true, // the rating is computed, the track is not rated
+		}},
+	}
+	for _, tt := range tests {
+		got, ok := lib.Track(tt.id)
+		if !ok {
+			t.Errorf("Track(%s) not found", tt.id)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Track(%s)\n got %+v\nwant %+v", tt.id, got, tt.want)
+		}
+	}
+	if _, ok := lib.Track(""); ok {
+		t.Errorf("the track without persistent id is kept")
+	}
+
+	// in the order of the Track ID, not of the dict
+	if got, _ := lib.PlaylistTracks("P0", 0, 10); !reflect.DeepEqual(ids(got), []string{"T1", "T2", "T3"}) {
+		t.Errorf("library tracks = %v, want [T1 T2 T3]", ids(got))
+	}
+}
+
+func TestParsePlaylists(t *testing.T) {
+	lib := loadTestLibrary(t)
+
+	// the genius playlist is hidden, the library is hidden but kept
+	if got, want := ids(lib.Playlists()), []string{"P0", "F1", "P1", "P2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Playlists = %v, want %v", got, want)
+	}
+	if root := lib.Root(); root.Id != "P0" || root.TrackCount != 3 {
+		t.Errorf("Root = %+v, want P0 with 3 tracks", root)
+	}
+	if mix, _ := lib.Playlist("P1"); !mix.Favorited || mix.ParentId != "F1" || mix.TrackCount != 2 {
+		t.Errorf("Playlist(P1) = %+v, want loved, in F1, with 2 tracks", mix)
+	}
+	if folder, _ := lib.Playlist("F1"); !folder.Folder || folder.TrackCount != 3 {
+		t.Errorf("Playlist(F1) = %+v, want a folder with 3 tracks", folder)
+	}
+	if _, ok := lib.Playlist("PG"); ok {
+		t.Errorf("the hidden playlist is kept")
+	}
+
+	tests := []struct {
+		id            string
+		offset, limit int
+		want          []string
+		ok            bool
+	}{
+		{"P1", 0, 10, []string{"T3", "T1"}, true},       // the unknown track is dropped
+		{"F1", 0, 10, []string{"T3", "T1", "T2"}, true}, // the tracks of the playlists inside, once
+		{"P0", 1, 1, []string{"T2"}, true},
+		{"P0", 3, 10, []string{}, true},
+		{"P0", -1, 10, []string{}, true},
+		{"PG", 0, 10, nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := lib.PlaylistTracks(tt.id, tt.offset, tt.limit)
+		if ok != tt.ok || (ok && !reflect.DeepEqual(ids(got), tt.want)) {
+			t.Errorf("PlaylistTracks(%s, %d, %d) = %v, %v, want %v, %v", tt.id, tt.offset, tt.limit, ids(got), ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseWithoutMaster(t *testing.T) {
+	lib, err := Parse(strings.NewReader(`<plist><dict><key>Tracks</key><dict>
+		<key>2</key><dict><key>Track ID</key><integer>2</integer><key>Persistent ID</key><string>B</string></dict>
+		<key>1</key><dict><key>Track ID</key><integer>1</integer><key>Persistent ID</key><string>A</string></dict>
+	</dict></dict></plist>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := lib.Root()
+	if root.Id != rootId || root.TrackCount != 2 {
+		t.Errorf("Root = %+v, want %s with 2 tracks", root, rootId)
+	}
+	if got, _ := lib.PlaylistTracks(root.Id, 0, 10); !reflect.DeepEqual(ids(got), []string{"A", "B"}) {
+		t.Errorf("root tracks = %v, want [A B]", ids(got))
+	}
+
+	for _, export := range []string{"<plist><array/></plist>", "<plist><dict/></plist>"} {
+		if _, err := Parse(strings.NewReader(export)); err == nil {
+			t.Errorf("Parse(%s) succeed, want not a library export", export)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	lib := loadTestLibrary(t)
+
+	tests := []struct {
+		query string
+		field model.SearchField
+		limit int
+		want  []string
+	}{
+		{"song", model.SearchAll, 10, []string{"T1", "T2"}},
+		{"SONG", model.SearchSongs, 10, []string{"T1", "T2"}},
+		{"song", model.SearchSongs, 1, []string{"T1"}},
+		{"band", model.SearchAll, 10, []string{"T1", "T2", "T3"}},
+		{"friends", model.SearchArtists, 10, []string{"T1"}}, // by the album artist
+		{"other", model.SearchArtists, 10, []string{"T3"}},
+		{"other", model.SearchSongs, 10, []string{}},
+		{"album", model.SearchAlbums, 10, []string{"T1", "T2"}},
+		{"album", model.SearchArtists, 10, []string{}},
+		{" & ", model.SearchAll, 10, []string{"T1", "T3"}},
+		{"  ", model.SearchAll, 10, []string{}},
+	}
+	for _, tt := range tests {
+		if got := ids(lib.Search(tt.query, tt.field, tt.limit)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q, %s, %d) = %v, want %v", tt.query, tt.field, tt.limit, got, tt.want)
+		}
+	}
+
+	// the snapshot follow the changes
+	lib.Update("T3", func(track *model.Track) { track.Name = "Renamed Song" })
+	if got := ids(lib.Search("song", model.SearchSongs, 10)); !reflect.DeepEqual(got, []string{"T1", "T2", "T3"}) {
+		t.Errorf("Search after Update = %v, want [T1 T2 T3]", got)
+	}
+}
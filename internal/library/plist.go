@@ -0,0 +1,160 @@
+package library
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// decodePlist decode an XML property list into plain values:
+// map[string]any for <dict>, []any for <array>, string, int64, float64, bool, time.Time and []byte.
+func decodePlist(r io.Reader) (any, error) {
+	d := xml.NewDecoder(r)
+	// the export declare UTF-8, anything else is passed through as is
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) { return input, nil }
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, errors.New("no <plist> element")
+			}
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			if start.Name.Local != "plist" {
+				return nil, fmt.Errorf("unexpected <%s>, want <plist>", start.Name.Local)
+			}
+			start, err := nextStart(d)
+			if err != nil {
+				return nil, err
+			}
+			return decodeValue(d, start)
+		}
+	}
+}
+
+// nextStart skip to the next start element, an end element first is an error
+func nextStart(d *xml.Decoder) (xml.StartElement, error) {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			return tok, nil
+		case xml.EndElement:
+			return xml.StartElement{}, fmt.Errorf("unexpected </%s>", tok.Name.Local)
+		}
+	}
+}
+
+func decodeValue(d *xml.Decoder, start xml.StartElement) (any, error) {
+	switch start.Name.Local {
+	case "dict":
+		return decodeDict(d)
+	case "array":
+		return decodeArray(d)
+	case "true", "false":
+		if err := d.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	var text string
+	if err := d.DecodeElement(&text, &start); err != nil {
+		return nil, err
+	}
+	switch start.Name.Local {
+	case "string":
+		return text, nil
+	case "integer":
+		return strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	case "real":
+		return strconv.ParseFloat(strings.TrimSpace(text), 64)
+	case "date":
+		return time.Parse(time.RFC3339, strings.TrimSpace(text))
+	case "data":
+		return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+	}
+	return nil, fmt.Errorf("unknown plist element <%s>", start.Name.Local)
+}
+
+func decodeDict(d *xml.Decoder) (map[string]any, error) {
+	dict := map[string]any{}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.EndElement:
+			return dict, nil
+		case xml.StartElement:
+			if tok.Name.Local != "key" {
+				return nil, fmt.Errorf("unexpected <%s> in <dict>, want <key>", tok.Name.Local)
+			}
+			var key string
+			if err := d.DecodeElement(&key, &tok); err != nil {
+				return nil, err
+			}
+			start, err := nextStart(d)
+			if err != nil {
+				return nil, fmt.Errorf("value of %q: %w", key, err)
+			}
+			value, err := decodeValue(d, start)
+			if err != nil {
+				return nil, fmt.Errorf("value of %q: %w", key, err)
+			}
+			dict[key] = value
+		}
+	}
+}
+
+func decodeArray(d *xml.Decoder) ([]any, error) {
+	array := []any{}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.EndElement:
+			return array, nil
+		case xml.StartElement:
+			value, err := decodeValue(d, tok)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+	}
+}
+
+// dictXxx return the value of key, the zero value when it is missing or of another type
+
+func dictString(dict map[string]any, key string) string {
+	s, _ := dict[key].(string)
+	return s
+}
+
+func dictInt(dict map[string]any, key string) int {
+	i, _ := dict[key].(int64)
+	return int(i)
+}
+
+func dictBool(dict map[string]any, key string) bool {
+	b, _ := dict[key].(bool)
+	return b
+}
+
+func dictTime(dict map[string]any, key string) time.Time {
+	t, _ := dict[key].(time.Time)
+	return t
+}
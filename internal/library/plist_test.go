@@ -0,0 +1,87 @@
+package library
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodePlist(t *testing.T) {
+	f, err := os.Open("testdata/Library.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	value, err := decodePlist(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	export, ok := value.(map[string]any)
+	if !ok {
+		t.Fatalf("top level is %T, want a dict", value)
+	}
+
+	if got := export["Major Version"]; got != int64(1) {
+		t.Errorf("integer = %#v, want int64(1)", got)
+	}
+	if got := export["Application Version"]; got != "1.4.5.7" {
+		t.Errorf("string = %#v, want 1.4.5.7", got)
+	}
+	if got := export["Show Content Ratings"]; got != true {
+		t.Errorf("true = %#v, want true", got)
+	}
+	if got, want := export["Date"], time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC); got != want {
+		t.Errorf("date = %#v, want %v", got, want)
+	}
+
+	track := export["Tracks"].(map[string]any)["300"].(map[string]any)
+	if got := track["Name"]; got != "Third & Last" {
+		t.Errorf("escaped string = %#v, want Third & Last", got)
+	}
+	if got := export["Tracks"].(map[string]any)["200"].(map[string]any)["Volume Adjustment"]; got != -0.5 {
+		t.Errorf("real = %#v, want -0.5", got)
+	}
+
+	playlists, ok := export["Playlists"].([]any)
+	if !ok || len(playlists) != 5 {
+		t.Fatalf("array = %#v, want 5 playlists", export["Playlists"])
+	}
+	if got := playlists[0].(map[string]any)["Visible"]; got != false {
+		t.Errorf("false = %#v, want false", got)
+	}
+	// the data is wrapped on several lines
+	want := []byte{1, 1, 0, 3, 0, 0, 0, 2, 0, 0, 0, 0x19, 0, 0, 0, 0, 0, 0, 0, 7}
+	if got, _ := playlists[3].(map[string]any)["Smart Info"].([]byte); !bytes.Equal(got, want) {
+		t.Errorf("data = %#v, want %#v", got, want)
+	}
+	items := playlists[3].(map[string]any)["Playlist Items"].([]any)
+	if got, want := items[0], map[string]any{"Track ID": int64(300)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("array item = %#v, want %#v", got, want)
+	}
+}
+
+func TestDecodePlistErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+		want string // part of the error
+	}{
+		{"empty", "", "no <plist> element"},
+		{"another root", "<dict></dict>", "want <plist>"},
+		{"value without key", "<plist><dict><string>a</string></dict></plist>", "want <key>"},
+		{"key without value", "<plist><dict><key>a</key></dict></plist>", `value of "a"`},
+		{"unknown element", "<plist><dict><key>a</key><uid>1</uid></dict></plist>", "unknown plist element <uid>"},
+		{"bad integer", "<plist><integer>one</integer></plist>", "invalid syntax"},
+		{"bad date", "<plist><date>yesterday</date></plist>", "cannot parse"},
+		{"truncated", "<plist><array><true/>", "EOF"},
+	}
+	for _, tt := range tests {
+		_, err := decodePlist(strings.NewReader(tt.xml))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: decodePlist = %v, want an error with %q", tt.name, err, tt.want)
+		}
+	}
+}
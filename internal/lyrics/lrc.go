@@ -0,0 +1,211 @@
+// Package lyrics read time-synced lyrics from LRC files and find the file of a track.
+package lyrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lyrics is a parsed LRC file, the times of the lines and words already have the offset applied.
+type Lyrics struct {
+	Title  string
+	Artist string
+	Album  string
+	Length time.Duration // length of the song the lyrics were timed for, 0 when the file does not tell
+	Offset time.Duration // [offset:], a positive offset show the lines earlier
+	Synced bool          // false for a plain text file, every line is then at 0
+	Lines  []Line        // sorted by time
+}
+
+type Line struct {
+	Time  time.Duration
+	Text  string
+	Words []Word // only with enhanced LRC, the words join into Text
+}
+
+// Word is a word of enhanced LRC, like "<00:12.30>word ".
+type Word struct {
+	Time time.Duration
+	Text string
+}
+
+var (
+	// [mm:ss], [mm:ss.xx], [mm:ss.xxx] and the [mm:ss:xx] some editors write
+	timestampPattern = regexp.MustCompile(`^(\d+):(\d{1,2})(?:[.:](\d{1,3}))?$`)
+	wordPattern      = regexp.MustCompile(`<(\d+:\d{1,2}(?:[.:]\d{1,3})?)>`)
+)
+
+// Load parse the LRC file at path.
+func Load(path string) (*Lyrics, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening lyrics: %w", err)
+	}
+	defer f.Close()
+	lyrics, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing lyrics %s: %w", path, err)
+	}
+	return lyrics, nil
+}
+
+// Parse read LRC. Lines may carry several timestamps, "[00:12.00][00:45.00]chorus",
+// and unknown tags are ignored, as players do.
+func Parse(r io.Reader) (*Lyrics, error) {
+	lyrics := &Lyrics{Lines: []Line{}}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		text := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if text == "" {
+			continue
+		}
+
+		times := []time.Duration{}
+		for strings.HasPrefix(text, "[") {
+			end := strings.Index(text, "]")
+			if end < 0 {
+				break
+			}
+			tag := text[1:end]
+			if t, ok := parseTimestamp(tag); ok {
+				times = append(times, t)
+			} else if len(times) == 0 {
+				lyrics.parseTag(tag)
+			}
+			text = strings.TrimSpace(text[end+1:])
+		}
+
+		if len(times) == 0 {
+			if text != "" && !lyrics.Synced {
+				// a plain text file, or text before the first timestamp
+				lyrics.Lines = append(lyrics.Lines, Line{Text: text})
+			}
+			continue
+		}
+		if !lyrics.Synced {
+			// drop the untimed lines, they were a header
+			lyrics.Synced = true
+			lyrics.Lines = lyrics.Lines[:0]
+		}
+
+		line := parseWords(text, times[0])
+		for _, t := range times {
+			copied := Line{Time: t, Text: line.Text}
+			// the word times belong to the first timestamp, shift them for the repeats
+			for _, w := range line.Words {
+				copied.Words = append(copied.Words, Word{Time: w.Time + t - times[0], Text: w.Text})
+			}
+			lyrics.Lines = append(lyrics.Lines, copied)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if lyrics.Offset != 0 {
+		for i := range lyrics.Lines {
+			lyrics.Lines[i].Time = max(lyrics.Lines[i].Time-lyrics.Offset, 0)
+			for j := range lyrics.Lines[i].Words {
+				lyrics.Lines[i].Words[j].Time = max(lyrics.Lines[i].Words[j].Time-lyrics.Offset, 0)
+			}
+		}
+	}
+	sort.SliceStable(lyrics.Lines, func(i, j int) bool { return lyrics.Lines[i].Time < lyrics.Lines[j].Time })
+	return lyrics, nil
+}
+
+// parseTag read the id tags, like [ar:artist] or [offset:+250]
+func (l *Lyrics) parseTag(tag string) {
+	key, value, ok := strings.Cut(tag, ":")
+	if !ok {
+		return
+	}
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case "ti":
+		l.Title = value
+	case "ar":
+		l.Artist = value
+	case "al":
+		l.Album = value
+	case "length":
+		if t, ok := parseTimestamp(value); ok {
+			l.Length = t
+		}
+	case "offset":
+		if ms, err := strconv.Atoi(strings.TrimPrefix(value, "+")); err == nil {
+			l.Offset = time.Duration(ms) * time.Millisecond
+		}
+	}
+}
+
+func parseTimestamp(s string) (time.Duration, bool) {
+	match := timestampPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return 0, false
+	}
+	minutes, _ := strconv.Atoi(match[1])
+	seconds, _ := strconv.Atoi(match[2])
+	t := time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+	if frac := match[3]; frac != "" {
+		// .5 is half a second, .05 and .050 are 50ms
+		n, _ := strconv.Atoi(frac)
+		for i := len(frac); i < 3; i++ {
+			n *= 10
+		}
+		t += time.Duration(n) * time.Millisecond
+	}
+	return t, true
+}
+
+// parseWords split the text of an enhanced line into its timed words.
+// The text before the first word time, if any, is a word sung at the time of the line.
+func parseWords(text string, lineTime time.Duration) Line {
+	marks := wordPattern.FindAllStringSubmatchIndex(text, -1)
+	if len(marks) == 0 {
+		return Line{Text: text}
+	}
+
+	line := Line{}
+	if head := text[:marks[0][0]]; strings.TrimSpace(head) != "" {
+		line.Words = append(line.Words, Word{Time: lineTime, Text: head})
+	}
+	for i, mark := range marks {
+		t, _ := parseTimestamp(text[mark[2]:mark[3]])
+		end := len(text)
+		if i+1 < len(marks) {
+			end = marks[i+1][0]
+		}
+		// a trailing time only mark the end of the last word
+		if word := text[mark[1]:end]; word != "" {
+			line.Words = append(line.Words, Word{Time: t, Text: word})
+		}
+	}
+	texts := make([]string, 0, len(line.Words))
+	for _, w := range line.Words {
+		texts = append(texts, w.Text)
+	}
+	line.Text = strings.TrimSpace(strings.Join(texts, ""))
+	return line
+}
+
+// LineAt return the index of the line sung at pos, -1 before the first line or when the lyrics are not synced.
+func (l *Lyrics) LineAt(pos time.Duration) int {
+	if !l.Synced {
+		return -1
+	}
+	// the first line after pos, the one before is sung
+	return sort.Search(len(l.Lines), func(i int) bool { return l.Lines[i].Time > pos }) - 1
+}
+
+// WordAt return the index of the word sung at pos, -1 before the first word or when the line has no word times.
+func (line Line) WordAt(pos time.Duration) int {
+	return sort.Search(len(line.Words), func(i int) bool { return line.Words[i].Time > pos }) - 1
+}
@@ -0,0 +1,154 @@
+package lyrics
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func ms(n int) time.Duration {
+	return time.Duration(n) * time.Millisecond
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		s    string
+		want time.Duration
+		ok   bool
+	}{
+		{"00:12", ms(12000), true},
+		{"01:02.5", ms(62500), true},   // 1 digit: tenths
+		{"01:02.05", ms(62050), true},  // 2 digits: hundredths
+		{"01:02.050", ms(62050), true}, // 3 digits: milliseconds
+		{"01:02:50", ms(62500), true},  // some editors write a colon
+		{"1:2", ms(62000), true},
+		{"120:00.00", 120 * time.Minute, true},
+		{" 00:01.00 ", ms(1000), true},
+		{"00:01.0000", 0, false},
+		{"00:123", 0, false},
+		{"ar:Band", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseTimestamp(tt.s)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseTimestamp(%q) = %v, %v, want %v, %v", tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		lrc  string
+		want *Lyrics
+	}{
+		{
+			name: "tags and lines",
+			lrc:  "\ufeff[ti:Song]\n[ar:Band]\n[al:Album]\n[length:03:20]\n[by:someone]\n\n[00:01.00]first\n[00:03.50]second\n",
+			want: &Lyrics{Title: "Song", Artist: "Band", Album: "Album", Length: ms(200000), Synced: true, Lines: []Line{
+				{Time: ms(1000), Text: "first"},
+				{Time: ms(3500), Text: "second"},
+			}},
+		},
+		{
+			name: "several timestamps on a line",
+			lrc:  "[00:10.00][00:30.00]chorus\n[00:20.00]verse\n",
+			want: &Lyrics{Synced: true, Lines: []Line{
+				{Time: ms(10000), Text: "chorus"},
+				{Time: ms(20000), Text: "verse"},
+				{Time: ms(30000), Text: "chorus"},
+			}},
+		},
+		{
+			name: "positive offset show the lines earlier",
+			lrc:  "[offset:+500]\n[00:00.20]a\n[00:02.00]b\n",
+			want: &Lyrics{Offset: ms(500), Synced: true, Lines: []Line{
+				{Time: 0, Text: "a"}, // not before the song start
+				{Time: ms(1500), Text: "b"},
+			}},
+		},
+		{
+			name: "negative offset show the lines later",
+			lrc:  "[offset:-250]\n[00:02.00]b\n",
+			want: &Lyrics{Offset: ms(-250), Synced: true, Lines: []Line{{Time: ms(2250), Text: "b"}}},
+		},
+		{
+			name: "text before the first timestamp is a header",
+			lrc:  "Song by Band\nwritten by someone\n[00:01.00]first\n",
+			want: &Lyrics{Synced: true, Lines: []Line{{Time: ms(1000), Text: "first"}}},
+		},
+		{
+			name: "plain text",
+			lrc:  "first\n\nsecond\n",
+			want: &Lyrics{Lines: []Line{{Text: "first"}, {Text: "second"}}},
+		},
+		{
+			name: "unknown tags are ignored",
+			lrc:  "[00:01.00][x]text\n[x:y]\n",
+			want: &Lyrics{Synced: true, Lines: []Line{{Time: ms(1000), Text: "text"}}},
+		},
+		{
+			name: "enhanced word times",
+			lrc:  "[00:10.00]<00:10.00>Hello <00:10.50>world<00:11.00>\n",
+			want: &Lyrics{Synced: true, Lines: []Line{{Time: ms(10000), Text: "Hello world", Words: []Word{
+				{Time: ms(10000), Text: "Hello "},
+				{Time: ms(10500), Text: "world"},
+			}}}},
+		},
+		{
+			name: "enhanced words shift with the repeats and the offset",
+			lrc:  "[offset:100]\n[00:10.00][00:20.00]la <00:10.50>la\n",
+			want: &Lyrics{Offset: ms(100), Synced: true, Lines: []Line{
+				{Time: ms(9900), Text: "la la", Words: []Word{{Time: ms(9900), Text: "la "}, {Time: ms(10400), Text: "la"}}},
+				{Time: ms(19900), Text: "la la", Words: []Word{{Time: ms(19900), Text: "la "}, {Time: ms(20400), Text: "la"}}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		got, err := Parse(strings.NewReader(tt.lrc))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLineAt(t *testing.T) {
+	l := &Lyrics{Synced: true, Lines: []Line{
+		{Time: ms(1000), Words: []Word{{Time: ms(1000)}, {Time: ms(1500)}}},
+		{Time: ms(3000)},
+		{Time: ms(3000)},
+	}}
+	tests := []struct {
+		pos  time.Duration
+		want int
+	}{
+		{0, -1},
+		{ms(999), -1},
+		{ms(1000), 0},
+		{ms(2999), 0},
+		{ms(3000), 2}, // the last of the lines sharing a time
+		{time.Hour, 2},
+	}
+	for _, tt := range tests {
+		if got := l.LineAt(tt.pos); got != tt.want {
+			t.Errorf("LineAt(%v) = %d, want %d", tt.pos, got, tt.want)
+		}
+	}
+
+	if got := (&Lyrics{Lines: []Line{{Text: "plain"}}}).LineAt(time.Hour); got != -1 {
+		t.Errorf("LineAt of plain lyrics = %d, want -1", got)
+	}
+
+	words := l.Lines[0]
+	for pos, want := range map[time.Duration]int{ms(999): -1, ms(1000): 0, ms(1499): 0, ms(1500): 1} {
+		if got := words.WordAt(pos); got != want {
+			t.Errorf("WordAt(%v) = %d, want %d", pos, got, want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package lyrics
+
+import (
+	"errors"
+	"io/fs"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ErrNotFound is returned when no lyrics file match the track.
+var ErrNotFound = errors.New("no lyrics file for the track")
+
+// lengthTolerance is how far the [length:] of a file can be from the duration of the track,
+// a bigger gap is another version of the song, like a live one
+const lengthTolerance = 3 * time.Second
+
+// Resolver find the LRC file of a track in a few directories.
+// A track whose id is a path, like the songs of mpd, use the file next to it when there is one,
+// other tracks are matched by artist, title and duration against the tags or the name of the files,
+// "Artist - Title.lrc" or "Title.lrc".
+// It is safe for concurrent use.
+type Resolver struct {
+	dirs []string
+
+	mu      sync.Mutex
+	entries []entry // nil until the directories were scanned
+}
+
+// entry is a LRC file found while scanning
+type entry struct {
+	path   string
+	artist string // normalized
+	title  string // normalized
+	length time.Duration
+}
+
+func NewResolver(dirs ...string) *Resolver {
+	return &Resolver{dirs: dirs}
+}
+
+// Rescan forget the files found so far, the directories are scanned again on the next Resolve.
+func (r *Resolver) Rescan() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+// Resolve return the lyrics of the track and the file they were read from.
+func (r *Resolver) Resolve(track model.Track) (*Lyrics, string, error) {
+	for _, path := range r.besideTrack(track) {
+		if lyrics, err := Load(path); err == nil {
+			return lyrics, path, nil
+		}
+	}
+
+	title := normalize(track.Name)
+	bareTitle := normalize(stripBrackets(track.Name))
+	artists := []string{normalize(track.Artist), normalize(track.AlbumArtist)}
+	duration := time.Duration(track.Duration * float64(time.Second))
+
+	best, bestScore := "", 0
+	for _, e := range r.scan() {
+		if e.title != title && e.title != bareTitle {
+			continue
+		}
+		if e.length > 0 && duration > 0 && (e.length-duration).Abs() > lengthTolerance {
+			continue
+		}
+		score := 1
+		if e.artist != "" {
+			if !matchArtist(e.artist, artists) {
+				continue
+			}
+			score += 2
+		}
+		if e.length > 0 && duration > 0 {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = e.path, score
+		}
+	}
+	if best == "" {
+		return nil, "", ErrNotFound
+	}
+	lyrics, err := Load(best)
+	if err != nil {
+		return nil, "", err
+	}
+	return lyrics, best, nil
+}
+
+// besideTrack return where the LRC of a track identified by its path would be
+func (r *Resolver) besideTrack(track model.Track) []string {
+	ext := filepath.Ext(track.Id)
+	if ext == "" || !strings.Contains(track.Id, "/") || strings.Contains(track.Id, "://") {
+		return nil
+	}
+	paths := []string{}
+	for _, dir := range r.dirs {
+		paths = append(paths, filepath.Join(dir, filepath.FromSlash(strings.TrimSuffix(track.Id, ext)+".lrc")))
+	}
+	return paths
+}
+
+// scan index every LRC file of the directories, once
+func (r *Resolver) scan() []entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.entries != nil {
+		return r.entries
+	}
+
+	r.entries = []entry{}
+	for _, dir := range r.dirs {
+		// an unreadable directory only miss its lyrics
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".lrc") {
+				return nil
+			}
+			r.entries = append(r.entries, newEntry(path))
+			return nil
+		})
+	}
+	return r.entries
+}
+
+func newEntry(path string) entry {
+	e := entry{path: path}
+	if lyrics, err := Load(path); err == nil {
+		e.artist = normalize(lyrics.Artist)
+		e.title = normalize(lyrics.Title)
+		e.length = lyrics.Length
+	}
+	if e.title == "" {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if artist, title, ok := strings.Cut(name, " - "); ok {
+			e.artist, e.title = normalize(artist), normalize(title)
+		} else {
+			e.title = normalize(name)
+		}
+	}
+	return e
+}
+
+// normalize fold what differ between the tags of a file and of the player: case, punctuation and spacing
+func normalize(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// stripBrackets remove the "(Remastered 2011)" or "[Live]" following a title
+func stripBrackets(s string) string {
+	if i := strings.IndexAny(s, "(["); i > 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// matchArtist tell whether the artist of a file is one of the artists of the track,
+// "A & B" match a file of A
+func matchArtist(artist string, artists []string) bool {
+	for _, a := range artists {
+		if a == "" {
+			continue
+		}
+		if a == artist || strings.Contains(a, artist) || strings.Contains(artist, a) {
+			return true
+		}
+	}
+	return false
+}
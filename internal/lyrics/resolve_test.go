@@ -0,0 +1,73 @@
+package lyrics
+
+import (
+	"errors"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeLrc write a LRC file whose only line tell which file it is
+func writeLrc(t *testing.T, dir, name, tags string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(tags+"[00:01.00]"+name+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolver(t *testing.T) {
+	music, downloads := t.TempDir(), t.TempDir()
+	writeLrc(t, music, "Band/Album/01 Song.lrc", "")
+	writeLrc(t, music, "tagged.lrc", "[ar:The Band]\n[ti:Tagged Song]\n[length:03:20]\n")
+	writeLrc(t, music, "live.lrc", "[ar:The Band]\n[ti:Tagged Song]\n[length:09:00]\n")
+	writeLrc(t, downloads, "lrc/Other Band - Named Song.lrc", "")
+	writeLrc(t, downloads, "Only Title.LRC", "")
+	writeLrc(t, downloads, "Band - Only Title.lrc", "")
+	r := NewResolver(music, downloads)
+
+	tests := []struct {
+		name  string
+		track model.Track
+		want  string // the line of the file found, empty for none
+	}{
+		{"next to the song", model.Track{Id: "Band/Album/01 Song.flac", Name: "Whatever"}, "Band/Album/01 Song.lrc"},
+		{"by the tags", model.Track{Id: "T1", Name: "Tagged Song", Artist: "The Band", Duration: 201}, "tagged.lrc"},
+		{"by the tags, case and punctuation folded", model.Track{Id: "T1", Name: "tagged song!", Artist: "the band", Duration: 198}, "tagged.lrc"},
+		{"another length is another version", model.Track{Id: "T1", Name: "Tagged Song", Artist: "The Band", Duration: 300}, ""},
+		{"by artist - title", model.Track{Id: "T2", Name: "Named Song (Remastered 2011)", Artist: "Other Band & Guest"}, "lrc/Other Band - Named Song.lrc"},
+		{"the artist of the name win over the title alone", model.Track{Id: "T3", Name: "Only Title", Artist: "Band"}, "Band - Only Title.lrc"},
+		{"by title", model.Track{Id: "T3", Name: "Only Title", Artist: "Nobody"}, "Only Title.LRC"},
+		{"another artist", model.Track{Id: "T2", Name: "Named Song", Artist: "Nobody"}, ""},
+	}
+	for _, tt := range tests {
+		lyrics, path, err := r.Resolve(tt.track)
+		if tt.want == "" {
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("%s: Resolve = %s, %v, want ErrNotFound", tt.name, path, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Resolve: %v", tt.name, err)
+			continue
+		}
+		if got := lyrics.Lines[0].Text; got != tt.want {
+			t.Errorf("%s: Resolve found %s (%s), want %s", tt.name, got, path, tt.want)
+		}
+	}
+
+	// the directories are scanned once, until Rescan
+	writeLrc(t, downloads, "New Song.lrc", "")
+	if _, _, err := r.Resolve(model.Track{Id: "T4", Name: "New Song"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Resolve before Rescan = %v, want ErrNotFound", err)
+	}
+	r.Rescan()
+	if _, _, err := r.Resolve(model.Track{Id: "T4", Name: "New Song"}); err != nil {
+		t.Errorf("Resolve after Rescan: %v", err)
+	}
+}
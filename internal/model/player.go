@@ -0,0 +1,45 @@
+package model
+
+type PlayerState string
+
+const (
+	PlayerNotRunning PlayerState = "not running"
+	PlayerStopped    PlayerState = "stopped"
+	PlayerPlaying    PlayerState = "playing"
+	PlayerPaused     PlayerState = "paused"
+)
+
+// NowPlaying is a snapshot of the player taken at once.
+type NowPlaying struct {
+	Track    Track       `json:"track"`
+	State    PlayerState `json:"state"`
+	Position float64     `json:"position"` // seconds
+	Volume   int         `json:"volume"`
+	Shuffle  bool        `json:"shuffle"`
+	Repeat   RepeatMode  `json:"repeat"`
+	Playlist Playlist    `json:"playlist"` // identity only, without tracks
+}
+
+type RepeatMode string
+
+const (
+	RepeatOff RepeatMode = "off"
+	RepeatOne RepeatMode = "one"
+	RepeatAll RepeatMode = "all"
+)
+
+// Next return the mode after r, in the order Music.app cycle through: off -> all -> one
+func (r RepeatMode) Next() RepeatMode {
+	switch r {
+	case RepeatOff:
+		return RepeatAll
+	case RepeatAll:
+		return RepeatOne
+	default:
+		return RepeatOff
+	}
+}
+
+func (r RepeatMode) Valid() bool {
+	return r == RepeatOff || r == RepeatOne || r == RepeatAll
+}
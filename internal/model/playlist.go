@@ -1,7 +1,16 @@
 package model
 
 type Playlist struct {
-	Name      string
-	Favorited bool
-	Tracks    []Track
+	Id         string  `json:"id"`
+	Name       string  `json:"name"`
+	Favorited  bool    `json:"favorited"`
+	TrackCount int     `json:"trackCount"`
+	Stamp      string  `json:"stamp"`    // change whenever the tracks of the playlist change
+	ParentId   string  `json:"parentId"` // folder holding the playlist, empty at the top
+	Folder     bool    `json:"folder"`   // a folder of playlists, it list the tracks of every playlist inside
+	Tracks     []Track `json:"tracks"`
+}
+
+func (p Playlist) FilterValue() string {
+	return p.Name
 }
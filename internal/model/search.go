@@ -0,0 +1,29 @@
+package model
+
+// SearchField restrict a library search to one property, same as the `only` option of Music's search command.
+type SearchField string
+
+const (
+	SearchAll     SearchField = "all"
+	SearchSongs   SearchField = "songs"
+	SearchArtists SearchField = "artists"
+	SearchAlbums  SearchField = "albums"
+)
+
+// Next return the field after f, the search tab cycle through them.
+func (f SearchField) Next() SearchField {
+	switch f {
+	case SearchAll:
+		return SearchSongs
+	case SearchSongs:
+		return SearchArtists
+	case SearchArtists:
+		return SearchAlbums
+	default:
+		return SearchAll
+	}
+}
+
+func (f SearchField) Valid() bool {
+	return f == SearchAll || f == SearchSongs || f == SearchArtists || f == SearchAlbums
+}
@@ -1,16 +1,21 @@
 package model
 
+import "time"
+
 type Track struct {
-	Id          string
-	Name        string
-	Time        string
-	Duration    float64
-	PlayedCount int
-	Favorited   bool
-	Artist      string
-	Album       string
-	AlbumArtist string
-	Lyrics      string
+	Id          string    `json:"id"`
+	Name        string    `json:"name"`
+	Time        string    `json:"time"`
+	Duration    float64   `json:"duration"`
+	PlayedCount int       `json:"playedCount"`
+	Favorited   bool      `json:"favorited"`
+	Rating      int       `json:"rating"` // 0-100, 20 per star
+	Disliked    bool      `json:"disliked"`
+	Artist      string    `json:"artist"`
+	Album       string    `json:"album"`
+	AlbumArtist string    `json:"albumArtist"`
+	Lyrics      string    `json:"lyrics"`
+	DateAdded   time.Time `json:"dateAdded"` // zero when the source does not tell
 }
 
 func (t Track) FilterValue() string {
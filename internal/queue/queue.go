@@ -0,0 +1,64 @@
+package queue
+
+import (
+	"limiu82214/lazyAppleMusic/internal/model"
+	"slices"
+)
+
+// Queue is the Up Next list of the app, Music's scripting dictionary has no queue.
+// The tracks are played with PlayTrackById once the current track is over, see Watcher.
+type Queue struct {
+	tracks []model.Track
+}
+
+func New() *Queue {
+	return &Queue{}
+}
+
+// PlayNext put the track at the front of the queue.
+func (q *Queue) PlayNext(track model.Track) {
+	q.tracks = slices.Insert(q.tracks, 0, track)
+}
+
+// PlayLater put the track at the end of the queue.
+func (q *Queue) PlayLater(track model.Track) {
+	q.tracks = append(q.tracks, track)
+}
+
+// Pop remove and return the first track.
+func (q *Queue) Pop() (model.Track, bool) {
+	if len(q.tracks) == 0 {
+		return model.Track{}, false
+	}
+	track := q.tracks[0]
+	q.tracks = q.tracks[1:]
+	return track, true
+}
+
+// Take remove and return the track at i.
+func (q *Queue) Take(i int) (model.Track, bool) {
+	if i < 0 || i >= len(q.tracks) {
+		return model.Track{}, false
+	}
+	track := q.tracks[i]
+	q.tracks = slices.Delete(q.tracks, i, i+1)
+	return track, true
+}
+
+// Move move the track at from to the index to.
+func (q *Queue) Move(from, to int) bool {
+	if from < 0 || from >= len(q.tracks) || to < 0 || to >= len(q.tracks) {
+		return false
+	}
+	track := q.tracks[from]
+	q.tracks = slices.Insert(slices.Delete(q.tracks, from, from+1), to, track)
+	return true
+}
+
+func (q *Queue) Tracks() []model.Track {
+	return slices.Clone(q.tracks)
+}
+
+func (q *Queue) Len() int {
+	return len(q.tracks)
+}
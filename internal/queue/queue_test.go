@@ -0,0 +1,58 @@
+package queue
+
+import (
+	"limiu82214/lazyAppleMusic/internal/model"
+	"reflect"
+	"testing"
+)
+
+func queueIds(q *Queue) []string {
+	ids := []string{}
+	for _, track := range q.Tracks() {
+		ids = append(ids, track.Id)
+	}
+	return ids
+}
+
+func TestQueue(t *testing.T) {
+	q := New()
+	if _, ok := q.Pop(); ok {
+		t.Fatal("Pop of an empty queue succeed")
+	}
+
+	q.PlayLater(model.Track{Id: "L1"})
+	q.PlayLater(model.Track{Id: "L2"})
+	q.PlayNext(model.Track{Id: "N1"})
+	q.PlayNext(model.Track{Id: "N2"}) // the last asked to play next play first
+	if got, want := queueIds(q), []string{"N2", "N1", "L1", "L2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("queue = %v, want %v", got, want)
+	}
+
+	if track, ok := q.Pop(); !ok || track.Id != "N2" {
+		t.Errorf("Pop = %s, %v, want N2", track.Id, ok)
+	}
+	if !q.Move(2, 0) {
+		t.Error("Move(2, 0) failed")
+	}
+	if q.Move(0, 3) || q.Move(-1, 0) {
+		t.Error("Move out of the queue succeed")
+	}
+	if track, ok := q.Take(1); !ok || track.Id != "N1" {
+		t.Errorf("Take(1) = %s, %v, want N1", track.Id, ok)
+	}
+	if _, ok := q.Take(2); ok {
+		t.Error("Take out of the queue succeed")
+	}
+	if got, want := queueIds(q), []string{"L2", "L1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("queue = %v, want %v", got, want)
+	}
+
+	// Tracks is a copy
+	q.Tracks()[0].Id = "changed"
+	if track, _ := q.Pop(); track.Id != "L2" {
+		t.Errorf("Pop = %s, want L2", track.Id)
+	}
+	if q.Len() != 1 {
+		t.Errorf("Len = %d, want 1", q.Len())
+	}
+}
@@ -0,0 +1,55 @@
+package queue
+
+import "limiu82214/lazyAppleMusic/internal/model"
+
+// Watcher look at the now-playing snapshots and tell which queued track to play
+// when the current track ended or was skipped.
+type Watcher struct {
+	queue *Queue
+
+	seen        bool
+	lastTrackId string
+	expecting   bool
+}
+
+func NewWatcher(queue *Queue) *Watcher {
+	return &Watcher{queue: queue}
+}
+
+// Expect tell the watcher the next track change is asked by the user (or by the watcher
+// itself), so it doesn't play the queue over it.
+func (w *Watcher) Expect() {
+	w.expecting = true
+}
+
+// Cancel forget the expected track change, when the play asked failed the next change is not the app's own.
+func (w *Watcher) Cancel() {
+	w.expecting = false
+}
+
+// Observe return the track to play now, ok is false when the player should go on by itself.
+func (w *Watcher) Observe(nowPlaying model.NowPlaying) (track model.Track, ok bool) {
+	if nowPlaying.State == model.PlayerNotRunning {
+		w.seen = false
+		return model.Track{}, false
+	}
+
+	trackId := nowPlaying.Track.Id
+	changed := w.seen && trackId != w.lastTrackId
+	w.seen = true
+	w.lastTrackId = trackId
+	if !changed {
+		return model.Track{}, false
+	}
+	if w.expecting {
+		w.expecting = false
+		return model.Track{}, false
+	}
+	// the track ended, was skipped, or the playlist ended and the player stopped
+	track, ok = w.queue.Pop()
+	if ok {
+		// the switch to the popped track is not a skip
+		w.expecting = true
+	}
+	return track, ok
+}
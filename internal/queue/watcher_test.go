@@ -0,0 +1,52 @@
+package queue
+
+import (
+	"limiu82214/lazyAppleMusic/internal/model"
+	"testing"
+)
+
+func playing(trackId string) model.NowPlaying {
+	return model.NowPlaying{State: model.PlayerPlaying, Track: model.Track{Id: trackId}}
+}
+
+func TestWatcher(t *testing.T) {
+	q := New()
+	w := NewWatcher(q)
+	q.PlayLater(model.Track{Id: "Q1"})
+	q.PlayLater(model.Track{Id: "Q2"})
+	q.PlayLater(model.Track{Id: "Q3"})
+
+	steps := []struct {
+		name       string
+		expect     bool // the app ask a track change before the snapshot
+		cancel     bool // and the change failed
+		nowPlaying model.NowPlaying
+		want       string // the queued track to play, empty for none
+	}{
+		{name: "the first snapshot is not a change", nowPlaying: playing("A")},
+		{name: "same track", nowPlaying: playing("A")},
+		{name: "the track ended", nowPlaying: playing("B"), want: "Q1"},
+		{name: "the switch to the queued track", nowPlaying: playing("Q1")},
+		{name: "the user played a track", expect: true, nowPlaying: playing("C")},
+		{name: "the track was skipped", nowPlaying: playing("D"), want: "Q2"},
+		{name: "the switch to the queued track", nowPlaying: playing("Q2")},
+		{name: "the play of the user failed", expect: true, cancel: true, nowPlaying: playing("Q2")},
+		{name: "the next change play the queue", nowPlaying: playing("E"), want: "Q3"},
+		{name: "the switch to the queued track", nowPlaying: playing("Q3")},
+		{name: "Music quit", nowPlaying: model.NowPlaying{State: model.PlayerNotRunning}},
+		{name: "the first snapshot after Music came back", nowPlaying: playing("F")},
+		{name: "the queue is empty", nowPlaying: playing("G")},
+	}
+	for i, step := range steps {
+		if step.expect {
+			w.Expect()
+		}
+		if step.cancel {
+			w.Cancel()
+		}
+		track, ok := w.Observe(step.nowPlaying)
+		if ok != (step.want != "") || track.Id != step.want {
+			t.Errorf("step %d, %s: Observe = %s, %v, want %q", i, step.name, track.Id, ok, step.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package tui
+
+import (
+	"io"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type ConfirmTui interface {
+	tea.Model
+	Width(width int) ConfirmTui
+	Ask(prompt string, yes tea.Cmd)
+	IsOpen() bool
+}
+
+// confirmTui ask before a destructive action, the action run only on y.
+type confirmTui struct {
+	dump io.Writer
+
+	style  lipgloss.Style
+	prompt string
+	yes    tea.Cmd
+	open   bool
+}
+
+func newConfirmTui(dump io.Writer) ConfirmTui {
+	return &confirmTui{
+		dump:  dump,
+		style: lipgloss.NewStyle().Align(lipgloss.Center).Bold(true).Foreground(lipgloss.Color("205")),
+	}
+}
+
+// ======= MAIN
+
+func (m *confirmTui) Init() tea.Cmd {
+	return nil
+}
+
+func (m *confirmTui) View() string {
+	return m.style.Render(m.prompt + " (y/n)")
+}
+
+func (m *confirmTui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok && m.open {
+		switch msg.String() {
+		case "y", "Y":
+			yes := m.yes
+			m.close()
+			return m, yes
+		case "n", "N", "esc", "q":
+			m.close()
+		}
+	}
+	return m, nil
+}
+
+// ======= Other
+
+func (m *confirmTui) Ask(prompt string, yes tea.Cmd) {
+	m.prompt = prompt
+	m.yes = yes
+	m.open = true
+}
+
+func (m *confirmTui) close() {
+	m.open = false
+	m.prompt = ""
+	m.yes = nil
+}
+
+func (m *confirmTui) IsOpen() bool {
+	return m.open
+}
+
+func (m *confirmTui) Width(width int) ConfirmTui {
+	m.style = m.style.Width(width)
+	return m
+}
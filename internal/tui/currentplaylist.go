@@ -7,6 +7,7 @@ import (
 	"limiu82214/lazyAppleMusic/internal/constant"
 	"limiu82214/lazyAppleMusic/internal/model"
 	"limiu82214/lazyAppleMusic/internal/util"
+	"strconv"
 	"strings"
 
 	// "limiu82214/lazyAppleMusic/internal/bridge"
@@ -26,14 +27,17 @@ type CurrentPlaylistTui interface {
 	Height() int
 	IsFiltering() bool
 	IsUnFiltered() bool
+	NextPageOffset() (int, bool)
 }
 
 type currentPlaylistTui struct {
 	dump       io.Writer
 	appleMusic bridge.PlayerBridge
 
-	style lipgloss.Style
-	list  list.Model
+	style    lipgloss.Style
+	list     list.Model
+	playlist model.Playlist // identity of the loaded playlist, without tracks
+	height   int
 }
 
 func newCurrentPlaylistTui(dump io.Writer, bridge bridge.PlayerBridge) CurrentPlaylistTui {
@@ -65,7 +69,16 @@ func (m *currentPlaylistTui) Init() tea.Cmd {
 }
 
 func (m *currentPlaylistTui) View() string {
-	return m.style.Render(m.list.View())
+	if _, loading := m.NextPageOffset(); !loading {
+		return m.style.Render(m.list.View())
+	}
+
+	// keep one line for the loading indicator
+	m.list.SetHeight(m.height - 1)
+	defer m.list.SetHeight(m.height)
+	indicator := lipgloss.NewStyle().Faint(true).PaddingLeft(2).
+		Render(fmt.Sprintf("Loading... %d/%d", len(m.list.Items()), m.playlist.TrackCount))
+	return m.style.Render(lipgloss.JoinVertical(lipgloss.Left, m.list.View(), indicator))
 }
 
 func (m *currentPlaylistTui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -93,12 +106,7 @@ func (m *currentPlaylistTui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case constant.ShouldClearFilter:
 		m.list.ResetFilter()
 	case constant.EventUpdateCurrentPlaylist:
-		currentPlaylist := model.Playlist(msg)
-		items := make([]list.Item, len(currentPlaylist.Tracks))
-		for i := range currentPlaylist.Tracks {
-			items[i] = currentPlaylist.Tracks[i]
-		}
-		m.list.SetItems(items)
+		return m, m.updatePage(msg)
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "k":
@@ -110,11 +118,50 @@ func (m *currentPlaylistTui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "l":
 			m.list.NextPage()
 		case "f":
-			track := m.list.SelectedItem().(model.Track)
+			track, ok := m.selectedTrack()
+			if !ok {
+				return m, nil
+			}
 			return m, util.ToTeaCmdMsg(constant.ShouldFavoriteTrackId(track.Id))
+		case "0", "1", "2", "3", "4", "5":
+			track, ok := m.selectedTrack()
+			if !ok {
+				return m, nil
+			}
+			return m, util.ToTeaCmdMsg(constant.ShouldRateTrack{TrackId: track.Id, Rating: starsToRating(msg.String())})
+		case "D":
+			track, ok := m.selectedTrack()
+			if !ok {
+				return m, nil
+			}
+			return m, util.ToTeaCmdMsg(constant.ShouldSetTrackDisliked{TrackId: track.Id, Disliked: !track.Disliked})
+		case "e", "E":
+			track, ok := m.selectedTrack()
+			if !ok {
+				return m, nil
+			}
+			return m, util.ToTeaCmdMsg(constant.ShouldQueueTrack{Track: track, Next: msg.String() == "e"})
+		case "a":
+			track, ok := m.selectedTrack()
+			if !ok {
+				return m, nil
+			}
+			return m, util.ToTeaCmdMsg(constant.ShouldAddTracksToPlaylist{track})
+		case "x":
+			track, ok := m.selectedTrack()
+			if !ok {
+				return m, nil
+			}
+			return m, util.ToTeaCmdMsg(constant.ShouldRemoveTrackFromPlaylist{Playlist: m.playlist, Track: track})
 		case "g":
-			track := m.list.SelectedItem().(model.Track)
-			return m, util.ToTeaCmdMsg(constant.ShouldPlayTrackId(track.Id))
+			track, ok := m.selectedTrack()
+			if !ok {
+				return m, nil
+			}
+			return m, util.ToTeaCmdMsg(constant.ShouldPlayTrackInPlaylist{
+				PlaylistId: m.playlist.Id,
+				TrackId:    track.Id,
+			})
 
 		case "/":
 			spew.Fprintln(m.dump, "currentplaylist: show filter", m.list.ShowFilter(), m.list.FilteringEnabled(), m.list.ShowStatusBar())
@@ -129,20 +176,11 @@ func (m *currentPlaylistTui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case constant.EventFavoriteTrackId:
-		items := m.list.Items()
-		index := -1
-		for i := range items {
-			item := items[i].(model.Track)
-			if item.Id == string(msg) {
-				index = i
-				break
-			}
-		}
-		if index > 0 {
-			tmp := m.list.Items()[index].(model.Track)
-			tmp.Favorited = !tmp.Favorited
-			m.list.SetItem(index, tmp)
-		}
+		updateListTrack(&m.list, string(msg), toggleFavorited)
+	case constant.EventTrackRatingChanged:
+		updateListTrack(&m.list, msg.TrackId, func(t *model.Track) { t.Rating = msg.Rating })
+	case constant.EventTrackDislikedChanged:
+		updateListTrack(&m.list, msg.TrackId, func(t *model.Track) { setDisliked(t, msg.Disliked) })
 	default:
 		var cmd tea.Cmd
 		m.list, cmd = m.list.Update(msg)
@@ -154,12 +192,52 @@ func (m *currentPlaylistTui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // ======= Other
 
+// selectedTrack return the track under the cursor, ok is false on the placeholder shown before the first page
+func (m *currentPlaylistTui) selectedTrack() (model.Track, bool) {
+	track, ok := m.list.SelectedItem().(model.Track)
+	if !ok || track.Id == "" {
+		return model.Track{}, false
+	}
+	return track, true
+}
+
+// updatePage put a page of tracks into the list. The first page replace the whole list,
+// the others are appended when they continue the loaded tracks of the same playlist.
+func (m *currentPlaylistTui) updatePage(page constant.EventUpdateCurrentPlaylist) tea.Cmd {
+	items := []list.Item{}
+	switch {
+	case page.Offset == 0:
+	case page.Playlist.Id == m.playlist.Id && page.Offset == len(m.list.Items()):
+		items = m.list.Items()
+	default:
+		spew.Fprintln(m.dump, "currentplaylist: drop stale page", page.Playlist.Id, page.Offset)
+		return nil
+	}
+
+	for _, track := range page.Playlist.Tracks {
+		items = append(items, track)
+	}
+	m.playlist = page.Playlist
+	m.playlist.Tracks = nil
+	return m.list.SetItems(items)
+}
+
+// NextPageOffset return where the next page start, ok is false once the whole playlist is loaded.
+func (m *currentPlaylistTui) NextPageOffset() (int, bool) {
+	loaded := len(m.list.Items())
+	if m.playlist.Id == "" {
+		return 0, false
+	}
+	return loaded, loaded < m.playlist.TrackCount
+}
+
 func (m *currentPlaylistTui) SetWidth(width int) CurrentPlaylistTui {
 	m.list.SetWidth(width)
 	m.style = m.style.Width(width)
 	return m
 }
 func (m *currentPlaylistTui) SetHeight(height int) CurrentPlaylistTui {
+	m.height = height
 	m.list.SetHeight(height)
 	m.style = m.style.Height(height)
 	return m
@@ -174,6 +252,9 @@ func (m currentPlaylistTui) Height() int {
 func (m currentPlaylistTui) IsFiltering() bool {
 	return m.list.FilterState() == list.Filtering
 }
+func (m currentPlaylistTui) IsCapturingInput() bool {
+	return m.IsFiltering()
+}
 func (m currentPlaylistTui) IsUnFiltered() bool {
 	return m.list.FilterState() == list.Unfiltered
 }
@@ -195,6 +276,7 @@ func (d currentPlayListDelegate) Render(w io.Writer, m list.Model, index int, li
 	} else {
 		row = constant.Unfavorite + " " + i.Name + " - " + i.Artist
 	}
+	row += trackMarksUi(i)
 
 	fn := lipgloss.NewStyle().PaddingLeft(4).Render
 	if index == m.Index() {
@@ -205,3 +287,50 @@ func (d currentPlayListDelegate) Render(w io.Writer, m list.Model, index int, li
 
 	fmt.Fprint(w, fn(row))
 }
+
+// ======= track helpers, shared by the track lists
+
+// updateListTrack apply update to the track with id, when it is in the list
+func updateListTrack(l *list.Model, id string, update func(t *model.Track)) {
+	for i, item := range l.Items() {
+		if track, ok := item.(model.Track); ok && track.Id == id {
+			update(&track)
+			l.SetItem(i, track)
+			return
+		}
+	}
+}
+
+// toggleFavorited mirror Music.app, a favorited track is not disliked anymore
+func toggleFavorited(t *model.Track) {
+	t.Favorited = !t.Favorited
+	if t.Favorited {
+		t.Disliked = false
+	}
+}
+
+// setDisliked mirror Music.app, a disliked track is not favorited anymore
+func setDisliked(t *model.Track, disliked bool) {
+	t.Disliked = disliked
+	if disliked {
+		t.Favorited = false
+	}
+}
+
+// starsToRating turn the "0" to "5" keys into a 0-100 rating
+func starsToRating(key string) int {
+	stars, _ := strconv.Atoi(strings.TrimPrefix(key, "alt+"))
+	return stars * 20
+}
+
+// trackMarksUi return the rating and the dislike mark of the track, with a leading space
+func trackMarksUi(t model.Track) string {
+	marks := ""
+	if stars := util.RatingUi(t.Rating); stars != "" {
+		marks += " " + stars
+	}
+	if t.Disliked {
+		marks += " " + constant.Disliked
+	}
+	return marks
+}
@@ -0,0 +1,42 @@
+package tui
+
+import (
+	"io"
+	"limiu82214/lazyAppleMusic/internal/constant"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestCurrentPlaylistTuiView(t *testing.T) {
+	b := newTestBridge(t)
+
+	m := newCurrentPlaylistTui(io.Discard, b)
+	m.SetWidth(testWidth).SetHeight(10)
+	assertGolden(t, "currentplaylist_loading", m.View())
+
+	m.Update(testPlaylistPage(t, b))
+	assertGolden(t, "currentplaylist", m.View())
+}
+
+// the keys on the placeholder shown before the first page do nothing
+func TestCurrentPlaylistTuiKeysWhileLoading(t *testing.T) {
+	b := newTestBridge(t)
+	m := newCurrentPlaylistTui(io.Discard, b)
+	m.SetWidth(testWidth).SetHeight(10)
+
+	for _, key := range []string{"f", "0", "5", "D", "e", "E", "a", "x", "g"} {
+		if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}); cmd != nil {
+			t.Errorf("%s on the placeholder sent %#v", key, cmd())
+		}
+	}
+
+	m.Update(testPlaylistPage(t, b))
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	if cmd == nil {
+		t.Fatal("f on a track sent nothing")
+	}
+	if got, want := cmd(), constant.ShouldFavoriteTrackId(testLibrary.Tracks[0].Id); got != want {
+		t.Errorf("f on the first track sent %#v, want %#v", got, want)
+	}
+}
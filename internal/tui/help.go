@@ -26,6 +26,7 @@ func newHelpTui(dump io.Writer) HelpTui {
 		dump:  dump,
 		style: lipgloss.NewStyle().Align(lipgloss.Center),
 		content: "p: play/pause, " +
+			"o: launch Music, " +
 			"n: next, " +
 			"b: previous, " +
 			"u: volume up, " +
@@ -37,8 +38,22 @@ func newHelpTui(dump io.Writer) HelpTui {
 			"s: select current track, " +
 			"f: favorite selected track, " +
 			"F: favorite current track, " +
+			"0-5: rate selected track, " +
+			"alt+0-5: rate current track, " +
+			"D: dislike selected track, " +
+			"alt+D: dislike current track, " +
+			"z: toggle shuffle, " +
+			"R: cycle repeat, " +
+			"[/]: seek -/+ step, " +
+			"{/}: seek -/+ long step, " +
 			"g: play selected track, " +
-			"/: filter tracks, " +
+			"a: add selected track to playlist, " +
+			"x: remove selected track from playlist / queue, " +
+			"e/E: play selected track next / later, " +
+			"J/K: move queued track down / up, " +
+			"L: load lyrics from the player, " +
+			"/: filter tracks / search library, " +
+			"<enter>: jump to search result, " +
 			"<esc>: clear filter, " +
 			"<: prev list, " +
 			">: next list, " +
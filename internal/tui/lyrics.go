@@ -0,0 +1,327 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"io"
+	"limiu82214/lazyAppleMusic/internal/bridge"
+	"limiu82214/lazyAppleMusic/internal/constant"
+	"limiu82214/lazyAppleMusic/internal/lyrics"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/davecgh/go-spew/spew"
+)
+
+var lyricsDebug = false
+
+const lyricsTabName = "Lyrics"
+
+// lyricsTickInterval is how often the highlight of synced lyrics move, the player position only come every second
+const lyricsTickInterval = 200 * time.Millisecond
+
+type LyricsTui interface {
+	tea.Model
+	SetWidth(width int) LyricsTui
+	SetHeight(height int) LyricsTui
+}
+
+// lyricsTui show the lyrics of the current track. The lyrics come with the now-playing snapshot,
+// L load them again from the player on demand.
+// With a resolver the LRC file of the track is preferred, its current line is highlighted and kept in view.
+type lyricsTui struct {
+	ctx        context.Context // cancelled when the TUI quit
+	dump       io.Writer
+	appleMusic bridge.PlayerBridge
+	resolver   *lyrics.Resolver // nil without lyrics directories
+
+	style    lipgloss.Style
+	viewport viewport.Model
+	width    int
+
+	track   model.Track
+	lyrics  string
+	synced  *lyrics.Lyrics
+	source  string // file of synced
+	loading bool
+	err     error
+
+	// the position of the playing view when it was last told, moved on by the time passed since while playing
+	position   time.Duration
+	positionAt time.Time
+	playing    bool
+	ticking    bool
+	line       int // current line of synced
+	word       int // current word of the current line
+}
+
+var (
+	lyricsSungStyle    = lipgloss.NewStyle().Faint(true)
+	lyricsCurrentStyle = lipgloss.NewStyle().Bold(true)
+	lyricsWordStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205"))
+)
+
+func newLyricsTui(ctx context.Context, dump io.Writer, bridge bridge.PlayerBridge, resolver *lyrics.Resolver) LyricsTui {
+	obj := &lyricsTui{
+		ctx:        ctx,
+		dump:       dump,
+		appleMusic: bridge,
+		resolver:   resolver,
+
+		viewport: viewport.New(0, 0),
+		line:     -1,
+		word:     -1,
+	}
+
+	if !lyricsDebug {
+		obj.dump = io.Discard
+	}
+	return obj
+}
+
+// ======= MAIN
+
+func (m *lyricsTui) Init() tea.Cmd {
+	return nil
+}
+
+func (m *lyricsTui) View() string {
+	status := ""
+	switch {
+	case m.track.Id == "":
+		status = "No track playing"
+	case m.loading:
+		status = "Loading lyrics..."
+	case m.err != nil:
+		status = "Error loading lyrics: " + m.err.Error()
+	case m.source != "":
+		status = m.track.Name + " - " + m.track.Artist + " (" + filepath.Base(m.source) + ")"
+	default:
+		status = m.track.Name + " - " + m.track.Artist
+	}
+	status = lipgloss.NewStyle().Faint(true).Render(status)
+
+	return m.style.Render(lipgloss.JoinVertical(lipgloss.Left, status, m.viewport.View()))
+}
+
+func (m *lyricsTui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	spew.Fprintln(m.dump, "lyrics: ", msg)
+
+	switch msg := msg.(type) {
+	case constant.EventUpdateNowPlaying:
+		track := msg.Track
+		if track.Id != m.track.Id {
+			m.track = track
+			m.lyrics = track.Lyrics
+			m.synced = nil
+			m.source = ""
+			m.loading = false
+			m.err = nil
+			m.line, m.word = -1, -1
+			m.sync()
+			m.viewport.GotoTop()
+			return m, m.resolve()
+		}
+	case constant.EventUpdateLyrics:
+		if msg.TrackId != m.track.Id {
+			spew.Fprintln(m.dump, "lyrics: drop stale lyrics", msg.TrackId)
+			return m, nil
+		}
+		m.loading = false
+		m.err = msg.Err
+		if msg.Err == nil {
+			m.lyrics = msg.Lyrics
+		}
+		if msg.Synced != nil {
+			m.synced = msg.Synced
+			m.source = msg.Source
+			m.line, m.word = -1, -1
+		}
+		m.sync()
+		return m, m.follow()
+	case constant.EventUpdatePlayerPosition:
+		m.position = msg.Position
+		m.positionAt = time.Now()
+		m.playing = msg.Playing
+		return m, m.follow()
+	case constant.LyricsTickMsg:
+		m.ticking = false
+		return m, m.follow()
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "k":
+			m.viewport.ScrollUp(1)
+		case "j":
+			m.viewport.ScrollDown(1)
+		case "h":
+			m.viewport.PageUp()
+		case "l":
+			m.viewport.PageDown()
+		case "L":
+			return m, m.load()
+		}
+	}
+	return m, nil
+}
+
+// ======= Other
+
+// resolve look for the LRC file of the current track
+func (m *lyricsTui) resolve() tea.Cmd {
+	if m.resolver == nil || m.track.Id == "" {
+		return nil
+	}
+	track := m.track
+	return func() tea.Msg {
+		synced, source, err := m.resolver.Resolve(track)
+		if err != nil && !errors.Is(err, lyrics.ErrNotFound) {
+			spew.Fprintln(m.dump, "Error resolving lyrics:", err)
+		}
+		return constant.EventUpdateLyrics{TrackId: track.Id, Lyrics: track.Lyrics, Synced: synced, Source: source}
+	}
+}
+
+// load ask the player for the whole track, for the lyrics the snapshot did not carry,
+// and look for a LRC file again, one may have been added since
+func (m *lyricsTui) load() tea.Cmd {
+	if m.track.Id == "" || m.loading {
+		return nil
+	}
+	m.loading = true
+	m.err = nil
+	track, shown := m.track, m.lyrics
+	return func() tea.Msg {
+		msg := constant.EventUpdateLyrics{TrackId: track.Id}
+		if m.resolver != nil {
+			m.resolver.Rescan()
+			msg.Synced, msg.Source, _ = m.resolver.Resolve(track)
+		}
+		full, err := m.appleMusic.GetTrackById(m.ctx, track.Id)
+		if err != nil {
+			spew.Fprintln(m.dump, "Error loading lyrics:", err)
+			if msg.Synced == nil {
+				msg.Err = err
+			}
+			// keep the lyrics already shown
+			msg.Lyrics = shown
+			return msg
+		}
+		msg.Lyrics = full.Lyrics
+		return msg
+	}
+}
+
+// follow move the highlight to the line sung now, and keep ticking while the track play
+func (m *lyricsTui) follow() tea.Cmd {
+	if m.synced == nil || !m.synced.Synced {
+		return nil
+	}
+	position := m.position
+	if m.playing {
+		position += time.Since(m.positionAt)
+	}
+	line := m.synced.LineAt(position)
+	word := -1
+	if line >= 0 {
+		word = m.synced.Lines[line].WordAt(position)
+	}
+	if line != m.line || word != m.word {
+		lineChanged := line != m.line
+		m.line, m.word = line, word
+		m.sync()
+		if lineChanged {
+			m.scrollToLine()
+		}
+	}
+
+	if !m.playing || m.ticking {
+		return nil
+	}
+	m.ticking = true
+	return tea.Tick(lyricsTickInterval, func(t time.Time) tea.Msg { return constant.LyricsTickMsg(t) })
+}
+
+// sync wrap the lyrics to the width of the tab
+func (m *lyricsTui) sync() {
+	if m.width <= 0 {
+		return
+	}
+	if m.synced != nil && len(m.synced.Lines) > 0 {
+		m.viewport.SetContent(strings.Join(m.syncedRows(), "\n"))
+		return
+	}
+	content := strings.TrimSpace(strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(m.lyrics))
+	if content == "" {
+		content = lipgloss.NewStyle().Faint(true).Render("No lyrics, press L to load them from the player")
+	}
+	m.viewport.SetContent(lipgloss.NewStyle().Width(m.width).Render(content))
+}
+
+// syncedRows render every line of the LRC, the lines already sung are faint,
+// the words of the current line are lit as they are sung
+func (m *lyricsTui) syncedRows() []string {
+	wrap := lipgloss.NewStyle().Width(m.width)
+	rows := make([]string, 0, len(m.synced.Lines))
+	for i, line := range m.synced.Lines {
+		switch {
+		case i == m.line && len(line.Words) > 0:
+			text := ""
+			for j, w := range line.Words {
+				if j == 0 {
+					w.Text = strings.TrimLeft(w.Text, " ")
+				}
+				if j <= m.word {
+					text += lyricsWordStyle.Render(w.Text)
+				} else {
+					text += lyricsCurrentStyle.Render(w.Text)
+				}
+			}
+			rows = append(rows, wrap.Render(text))
+		case i == m.line:
+			rows = append(rows, wrap.Render(lyricsWordStyle.Render(line.Text)))
+		case i < m.line:
+			rows = append(rows, wrap.Render(lyricsSungStyle.Render(line.Text)))
+		default:
+			rows = append(rows, wrap.Render(line.Text))
+		}
+	}
+	return rows
+}
+
+// scrollToLine keep the current line a third down the view, a wrapped line take several rows
+func (m *lyricsTui) scrollToLine() {
+	if m.line < 0 {
+		m.viewport.GotoTop()
+		return
+	}
+	wrap := lipgloss.NewStyle().Width(m.width)
+	row := 0
+	for _, line := range m.synced.Lines[:m.line] {
+		row += lipgloss.Height(wrap.Render(line.Text))
+	}
+	m.viewport.SetYOffset(max(row-m.viewport.Height/3, 0))
+}
+
+func (m *lyricsTui) SetWidth(width int) LyricsTui {
+	if width != m.width {
+		m.width = width
+		m.viewport.Width = width
+		m.sync()
+		if m.synced != nil {
+			m.scrollToLine()
+		}
+	}
+	m.style = m.style.Width(width)
+	return m
+}
+func (m *lyricsTui) SetHeight(height int) LyricsTui {
+	// the status take a line
+	m.viewport.Height = max(height-1, 0)
+	m.style = m.style.Height(height)
+	return m
+}
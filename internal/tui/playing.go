@@ -6,6 +6,7 @@ import (
 	"limiu82214/lazyAppleMusic/internal/constant"
 	"limiu82214/lazyAppleMusic/internal/model"
 	"limiu82214/lazyAppleMusic/internal/util"
+	"strconv"
 	"time"
 
 	// "limiu82214/lazyAppleMusic/internal/bridge"
@@ -21,6 +22,11 @@ type PlayingTui interface {
 	Width(width int) PlayingTui
 	Height(height int) PlayingTui
 	GetCurrentTrack() model.Track
+	GetShuffle() bool
+	GetRepeat() model.RepeatMode
+	GetPlayerState() model.PlayerState
+	GetArtworkPath() string
+	GetPosition() time.Duration
 }
 
 type playingTui struct {
@@ -31,6 +37,12 @@ type playingTui struct {
 	style    lipgloss.Style
 	track    model.Track
 	albumImg string
+	// artworkPath is the file albumImg is rendered from
+	artworkPath string
+	shuffle     bool
+	repeat      model.RepeatMode
+	volume      int
+	state       model.PlayerState
 }
 
 func newPlayingTui(dump io.Writer, bridge bridge.PlayerBridge) PlayingTui {
@@ -44,6 +56,8 @@ func newPlayingTui(dump io.Writer, bridge bridge.PlayerBridge) PlayingTui {
 		playingTrackTimer: timer.NewWithInterval(0, time.Second),
 		track:             model.Track{},
 		albumImg:          "󰎃",
+		repeat:            model.RepeatOff,
+		state:             model.PlayerStopped,
 	}
 	if !playingDebug {
 		obj.dump = io.Discard
@@ -59,12 +73,18 @@ func (m *playingTui) Init() tea.Cmd {
 }
 
 func (m *playingTui) View() string {
-	viewStr := m.track.Name + " - " + m.track.Artist
+	if m.state == model.PlayerNotRunning {
+		return m.style.Render("Music is not running\n\npress o to launch it")
+	}
+
+	viewStr := m.stateView() + " " + m.track.Name + " - " + m.track.Artist + trackMarksUi(m.track)
 	if m.track.Favorited {
 		viewStr += " (" + constant.Favorite + ") "
 	} else {
 		viewStr += " (" + constant.Unfavorite + ") "
 	}
+	viewStr += m.playModeView()
+	viewStr += " " + constant.Volume + " " + strconv.Itoa(m.volume)
 	viewStr += " " + m.playingTrackTimer.Timeout.Abs().String() + " / "
 	viewStr += m.track.Time
 	playPercentage := (m.track.Duration - m.playingTrackTimer.Timeout.Seconds()) * 100 / m.track.Duration
@@ -79,23 +99,48 @@ func (m *playingTui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 
 	switch msg := msg.(type) {
-	case constant.EventUpdateTrackData:
-		m.track = model.Track(msg)
+	case constant.EventUpdateNowPlaying:
+		nowPlaying := model.NowPlaying(msg)
+		m.track = nowPlaying.Track
+		m.shuffle = nowPlaying.Shuffle
+		m.repeat = nowPlaying.Repeat
+		m.volume = nowPlaying.Volume
+		m.state = nowPlaying.State
+		return m, m.resetTimer(int(nowPlaying.Position))
+	case constant.EventUpdatePlayerState:
+		m.state = model.PlayerState(msg)
+		// keep the remaining time, only start or freeze the countdown
+		m.playingTrackTimer = timer.NewWithInterval(m.playingTrackTimer.Timeout, time.Second)
+		if m.state == model.PlayerPlaying {
+			return m, m.playingTrackTimer.Init()
+		}
+		return m, nil
 	case constant.EventUpdateCurrentAlbumImg:
 		m.albumImg = string(msg)
-	case constant.EventUpdatePlayerPosition:
-		pos := int(msg)
-		m.playingTrackTimer = timer.NewWithInterval(time.Duration(int(m.track.Duration)-pos)*time.Second, time.Second)
-		return m, m.playingTrackTimer.Init()
+	case constant.EventUpdateArtwork:
+		m.artworkPath = string(msg)
+		if m.artworkPath == "" {
+			m.albumImg = "󰎃"
+		}
+	case constant.EventUpdateShuffle:
+		m.shuffle = bool(msg)
+	case constant.EventUpdateRepeat:
+		m.repeat = model.RepeatMode(msg)
+	case constant.EventPlayerPositionChanged:
+		return m, m.resetTimer(int(msg))
 	case constant.EventFavoriteTrackId:
 		if m.track.Id == string(msg) {
-			if m.track.Favorited {
-				m.track.Favorited = false
-			} else {
-				m.track.Favorited = true
-			}
+			toggleFavorited(&m.track)
 			return m, nil
 		}
+	case constant.EventTrackRatingChanged:
+		if m.track.Id == msg.TrackId {
+			m.track.Rating = msg.Rating
+		}
+	case constant.EventTrackDislikedChanged:
+		if m.track.Id == msg.TrackId {
+			setDisliked(&m.track, msg.Disliked)
+		}
 
 	case timer.TickMsg:
 		switch msg.ID {
@@ -128,3 +173,56 @@ func (m *playingTui) Height(height int) PlayingTui {
 func (m playingTui) GetCurrentTrack() model.Track {
 	return m.track
 }
+func (m playingTui) GetArtworkPath() string {
+	return m.artworkPath
+}
+func (m playingTui) GetShuffle() bool {
+	return m.shuffle
+}
+func (m playingTui) GetRepeat() model.RepeatMode {
+	return m.repeat
+}
+func (m playingTui) GetPlayerState() model.PlayerState {
+	return m.state
+}
+
+// GetPosition return the position in the track, counted by the timer between two snapshots
+func (m playingTui) GetPosition() time.Duration {
+	return max(time.Duration(int(m.track.Duration))*time.Second-m.playingTrackTimer.Timeout, 0)
+}
+
+func (m playingTui) stateView() string {
+	switch m.state {
+	case model.PlayerPlaying:
+		return constant.Playing
+	case model.PlayerPaused:
+		return constant.Paused
+	default:
+		return constant.Stopped
+	}
+}
+
+// resetTimer rebuild the countdown of the playing track from the player position,
+// the countdown only run while playing
+func (m *playingTui) resetTimer(pos int) tea.Cmd {
+	m.playingTrackTimer = timer.NewWithInterval(time.Duration(int(m.track.Duration)-pos)*time.Second, time.Second)
+	if m.state != model.PlayerPlaying {
+		return nil
+	}
+	return m.playingTrackTimer.Init()
+}
+
+func (m playingTui) playModeView() string {
+	shuffle := constant.ShuffleOff
+	if m.shuffle {
+		shuffle = constant.ShuffleOn
+	}
+	repeat := constant.RepeatOff
+	switch m.repeat {
+	case model.RepeatAll:
+		repeat = constant.RepeatAll
+	case model.RepeatOne:
+		repeat = constant.RepeatOne
+	}
+	return shuffle + " " + repeat
+}
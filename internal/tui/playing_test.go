@@ -0,0 +1,24 @@
+package tui
+
+import (
+	"io"
+	"limiu82214/lazyAppleMusic/internal/constant"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"testing"
+)
+
+func TestPlayingTuiView(t *testing.T) {
+	b := newTestBridge(t)
+	nowPlaying := testNowPlaying(t, b)
+
+	m := newPlayingTui(io.Discard, b)
+	m.Update(nowPlaying)
+	assertGolden(t, "playing_paused", m.Width(testWidth).View())
+
+	m.Update(constant.EventUpdateRepeat(model.RepeatOne))
+	m.Update(constant.EventPlayerPositionChanged(60))
+	assertGolden(t, "playing_seeked", m.Width(testWidth).View())
+
+	m.Update(constant.EventUpdatePlayerState(model.PlayerNotRunning))
+	assertGolden(t, "playing_not_running", m.Width(testWidth).View())
+}
@@ -0,0 +1,274 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"limiu82214/lazyAppleMusic/internal/bridge"
+	"limiu82214/lazyAppleMusic/internal/constant"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/davecgh/go-spew/spew"
+)
+
+var playlistPickerDebug = false
+
+type PlaylistPickerTui interface {
+	tea.Model
+	SetWidth(width int) PlaylistPickerTui
+	SetHeight(height int) PlaylistPickerTui
+	Open(tracks []model.Track) tea.Cmd
+	Close()
+	IsOpen() bool
+}
+
+// playlistPickerTui pick the user playlist the tracks are added to, the input fuzzy filter the playlists.
+type playlistPickerTui struct {
+	ctx        context.Context // cancelled when the TUI quit
+	dump       io.Writer
+	appleMusic bridge.PlayerBridge
+
+	style lipgloss.Style
+	input textinput.Model
+	list  list.Model
+
+	open      bool
+	loading   bool
+	tracks    []model.Track
+	playlists []model.Playlist
+}
+
+func newPlaylistPickerTui(ctx context.Context, dump io.Writer, bridge bridge.PlayerBridge) PlaylistPickerTui {
+	input := textinput.New()
+	input.Placeholder = "playlist name"
+	input.Prompt = "> "
+
+	list := list.New([]list.Item{}, playlistPickerDelegate{}, 0, 0)
+	list.SetShowTitle(false)
+	list.SetShowHelp(false)
+	list.SetShowStatusBar(false)
+	list.SetShowPagination(true)
+	list.SetFilteringEnabled(false)
+
+	obj := &playlistPickerTui{
+		ctx:        ctx,
+		dump:       dump,
+		appleMusic: bridge,
+		style:      lipgloss.NewStyle().Border(lipgloss.RoundedBorder()),
+
+		input: input,
+		list:  list,
+	}
+
+	if !playlistPickerDebug {
+		obj.dump = io.Discard
+	}
+	return obj
+}
+
+// ======= MAIN
+
+func (m *playlistPickerTui) Init() tea.Cmd {
+	return nil
+}
+
+func (m *playlistPickerTui) View() string {
+	title := "Add to playlist"
+	if len(m.tracks) == 1 {
+		title = fmt.Sprintf("Add %q to playlist", m.tracks[0].Name)
+	}
+
+	hint := "<enter>: add, <esc>: cancel"
+	if name := strings.TrimSpace(m.input.Value()); name != "" {
+		hint = fmt.Sprintf("<enter>: add, <ctrl+n>: new playlist %q, <esc>: cancel", name)
+	}
+	body := m.list.View()
+	if m.loading {
+		body = "Loading..."
+	}
+
+	faint := lipgloss.NewStyle().Faint(true)
+	return m.style.Render(lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.NewStyle().Bold(true).Render(title),
+		m.input.View(),
+		body,
+		faint.Render(hint),
+	))
+}
+
+func (m *playlistPickerTui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	spew.Fprintln(m.dump, "playlistpicker: ", msg)
+
+	switch msg := msg.(type) {
+	case constant.EventUpdateUserPlaylists:
+		m.loading = false
+		m.playlists = msg
+		return m, m.filter()
+	case tea.KeyMsg:
+		if !m.open {
+			return m, nil
+		}
+		switch msg.String() {
+		case "esc":
+			m.Close()
+			return m, nil
+		case "up", "ctrl+k":
+			m.list.CursorUp()
+			return m, nil
+		case "down", "ctrl+j":
+			m.list.CursorDown()
+			return m, nil
+		case "enter":
+			playlist, ok := m.list.SelectedItem().(model.Playlist)
+			if !ok {
+				return m, nil
+			}
+			cmd := pickerFailed(m.appleMusic.AddTracksToPlaylist(m.ctx, playlist.Id, m.trackIds()))
+			m.Close()
+			return m, cmd
+		case "ctrl+n":
+			name := strings.TrimSpace(m.input.Value())
+			if name == "" {
+				return m, nil
+			}
+			cmd := pickerFailed(m.createAndAdd(name, m.trackIds()))
+			m.Close()
+			return m, cmd
+		}
+
+		value := m.input.Value()
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		if m.input.Value() != value {
+			return m, tea.Batch(cmd, m.filter())
+		}
+		return m, cmd
+	default:
+		if m.open {
+			var cmd tea.Cmd
+			m.input, cmd = m.input.Update(msg)
+			return m, cmd
+		}
+	}
+	return m, nil
+}
+
+// ======= Other
+
+// Open show the picker for tracks and load the user playlists.
+func (m *playlistPickerTui) Open(tracks []model.Track) tea.Cmd {
+	m.open = true
+	m.loading = true
+	m.tracks = tracks
+	m.input.Reset()
+	return tea.Batch(m.input.Focus(), func() tea.Msg {
+		playlists, err := m.appleMusic.GetUserPlaylists(m.ctx)
+		if err != nil {
+			spew.Fprintln(m.dump, "Error fetching user playlists:", err)
+			return constant.EventPlaylistPickerFailed{Err: err}
+		}
+		return constant.EventUpdateUserPlaylists(playlists)
+	})
+}
+
+// pickerFailed report the error answered by cmd as EventPlaylistPickerFailed
+func pickerFailed(cmd tea.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		msg := cmd()
+		if err, ok := msg.(error); ok {
+			return constant.EventPlaylistPickerFailed{Err: err}
+		}
+		return msg
+	}
+}
+
+func (m *playlistPickerTui) Close() {
+	m.open = false
+	m.input.Blur()
+	m.tracks = nil
+}
+
+func (m *playlistPickerTui) IsOpen() bool {
+	return m.open
+}
+
+func (m *playlistPickerTui) trackIds() []string {
+	ids := make([]string, 0, len(m.tracks))
+	for _, track := range m.tracks {
+		ids = append(ids, track.Id)
+	}
+	return ids
+}
+
+// filter keep the playlists matching the input, best match first
+func (m *playlistPickerTui) filter() tea.Cmd {
+	term := strings.TrimSpace(m.input.Value())
+	items := []list.Item{}
+	if term == "" {
+		for _, playlist := range m.playlists {
+			items = append(items, playlist)
+		}
+	} else {
+		names := make([]string, len(m.playlists))
+		for i, playlist := range m.playlists {
+			names[i] = playlist.FilterValue()
+		}
+		for _, rank := range list.DefaultFilter(term, names) {
+			items = append(items, m.playlists[rank.Index])
+		}
+	}
+	m.list.ResetSelected()
+	return m.list.SetItems(items)
+}
+
+// createAndAdd create the playlist then add the tracks to it, once the id of the new playlist is known
+func (m *playlistPickerTui) createAndAdd(name string, trackIds []string) tea.Cmd {
+	return func() tea.Msg {
+		msg := m.appleMusic.CreatePlaylist(m.ctx, name)()
+		created, ok := msg.(constant.EventPlaylistCreated)
+		if !ok || len(trackIds) == 0 {
+			return msg
+		}
+		return m.appleMusic.AddTracksToPlaylist(m.ctx, created.Id, trackIds)()
+	}
+}
+
+func (m *playlistPickerTui) SetWidth(width int) PlaylistPickerTui {
+	frame := m.style.GetHorizontalFrameSize()
+	m.input.Width = width - frame - lipgloss.Width(m.input.Prompt) - 1
+	m.list.SetWidth(width - frame)
+	m.style = m.style.Width(width - m.style.GetHorizontalBorderSize())
+	return m
+}
+func (m *playlistPickerTui) SetHeight(height int) PlaylistPickerTui {
+	// the title, the input and the hint take a line each
+	m.list.SetHeight(max(height-m.style.GetVerticalFrameSize()-3, 0))
+	m.style = m.style.Height(height - m.style.GetVerticalBorderSize())
+	return m
+}
+
+type playlistPickerDelegate struct{}
+
+func (d playlistPickerDelegate) Height() int                               { return 1 }
+func (d playlistPickerDelegate) Spacing() int                              { return 0 }
+func (d playlistPickerDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd { return nil }
+func (d playlistPickerDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	p, ok := listItem.(model.Playlist)
+	if !ok {
+		return
+	}
+
+	fn := lipgloss.NewStyle().PaddingLeft(4).Render
+	if index == m.Index() {
+		fn = func(s ...string) string {
+			return lipgloss.NewStyle().PaddingLeft(2).Bold(true).Foreground(lipgloss.Color("205")).Render("> " + strings.Join(s, " "))
+		}
+	}
+
+	fmt.Fprint(w, fn(p.Name))
+}
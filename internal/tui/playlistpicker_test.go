@@ -0,0 +1,45 @@
+package tui
+
+import (
+	"context"
+	"io"
+	"limiu82214/lazyAppleMusic/internal/artwork"
+	"limiu82214/lazyAppleMusic/internal/bridge"
+	"limiu82214/lazyAppleMusic/internal/constant"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// only the failures of the picker close it, not the errors of the polls or of the other tabs
+func TestPlaylistPickerCloseOnItsFailure(t *testing.T) {
+	b := newTestBridge(t)
+	var m tea.Model = InitialTopTui(context.Background(), io.Discard, b, Options{Artwork: artwork.KindASCII})
+	m, _ = m.Update(tea.WindowSizeMsg{Width: testWidth, Height: testHeight})
+	m, _ = m.Update(constant.ShouldAddTracksToPlaylist([]model.Track{testLibrary.Tracks[0]}))
+	if !m.(topTui).picker.IsOpen() {
+		t.Fatal("the picker did not open")
+	}
+
+	m, _ = m.Update(bridge.ErrTimeout)
+	if !m.(topTui).picker.IsOpen() {
+		t.Error("a poll error closed the picker")
+	}
+
+	m, _ = m.Update(constant.EventPlaylistPickerFailed{Err: bridge.ErrTimeout})
+	if m.(topTui).picker.IsOpen() {
+		t.Error("the picker stayed open after its playlists failed to load")
+	}
+}
+
+func TestPickerFailed(t *testing.T) {
+	msg := pickerFailed(func() tea.Msg { return bridge.ErrPlaylistNotFound })()
+	if failed, ok := msg.(constant.EventPlaylistPickerFailed); !ok || failed.Err != bridge.ErrPlaylistNotFound {
+		t.Errorf("pickerFailed = %#v, want EventPlaylistPickerFailed", msg)
+	}
+	msg = pickerFailed(func() tea.Msg { return constant.EventPlaylistChanged("P1") })()
+	if _, ok := msg.(constant.EventPlaylistChanged); !ok {
+		t.Errorf("pickerFailed = %#v, want the message of the command", msg)
+	}
+}
@@ -0,0 +1,155 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"limiu82214/lazyAppleMusic/internal/constant"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"limiu82214/lazyAppleMusic/internal/queue"
+	"limiu82214/lazyAppleMusic/internal/util"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/davecgh/go-spew/spew"
+)
+
+var queueDebug = false
+
+const queueTabName = "Up Next"
+
+type QueueTui interface {
+	tea.Model
+	SetWidth(width int) QueueTui
+	SetHeight(height int) QueueTui
+}
+
+// queueTui show the Up Next queue, the queue itself is shared with topTui which play it.
+type queueTui struct {
+	dump  io.Writer
+	queue *queue.Queue
+
+	style lipgloss.Style
+	list  list.Model
+}
+
+func newQueueTui(dump io.Writer, q *queue.Queue) QueueTui {
+	list := list.New([]list.Item{}, queueDelegate{}, 0, 0)
+	list.SetShowTitle(false)
+	list.SetShowHelp(false)
+	list.SetShowStatusBar(false)
+	list.SetShowPagination(true)
+	list.SetFilteringEnabled(false)
+
+	obj := &queueTui{
+		dump:  dump,
+		queue: q,
+
+		list: list,
+	}
+
+	if !queueDebug {
+		obj.dump = io.Discard
+	}
+	return obj
+}
+
+// ======= MAIN
+
+func (m *queueTui) Init() tea.Cmd {
+	return nil
+}
+
+func (m *queueTui) View() string {
+	if m.queue.Len() == 0 {
+		return m.style.Render(lipgloss.NewStyle().Faint(true).PaddingLeft(2).
+			Render("Nothing queued, press e to play a track next or E to play it later"))
+	}
+	return m.style.Render(m.list.View())
+}
+
+func (m *queueTui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	spew.Fprintln(m.dump, "queue: ", msg)
+
+	switch msg := msg.(type) {
+	case constant.EventQueueChanged:
+		return m, m.sync()
+	case tea.KeyMsg:
+		index := m.list.Index()
+		switch msg.String() {
+		case "k":
+			m.list.CursorUp()
+		case "j":
+			m.list.CursorDown()
+		case "h":
+			m.list.PrevPage()
+		case "l":
+			m.list.NextPage()
+		case "K":
+			if m.queue.Move(index, index-1) {
+				m.list.CursorUp()
+				return m, m.sync()
+			}
+		case "J":
+			if m.queue.Move(index, index+1) {
+				m.list.CursorDown()
+				return m, m.sync()
+			}
+		case "x":
+			if _, ok := m.queue.Take(index); ok {
+				return m, m.sync()
+			}
+		case "g":
+			if track, ok := m.queue.Take(index); ok {
+				return m, tea.Batch(m.sync(), util.ToTeaCmdMsg(constant.ShouldPlayTrackId(track.Id)))
+			}
+		}
+	}
+	return m, nil
+}
+
+// ======= Other
+
+// sync show the tracks of the queue
+func (m *queueTui) sync() tea.Cmd {
+	items := []list.Item{}
+	for _, track := range m.queue.Tracks() {
+		items = append(items, track)
+	}
+	return m.list.SetItems(items)
+}
+
+func (m *queueTui) SetWidth(width int) QueueTui {
+	m.list.SetWidth(width)
+	m.style = m.style.Width(width)
+	return m
+}
+func (m *queueTui) SetHeight(height int) QueueTui {
+	m.list.SetHeight(height)
+	m.style = m.style.Height(height)
+	return m
+}
+
+type queueDelegate struct{}
+
+func (d queueDelegate) Height() int                               { return 1 }
+func (d queueDelegate) Spacing() int                              { return 0 }
+func (d queueDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd { return nil }
+func (d queueDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	i, ok := listItem.(model.Track)
+	if !ok {
+		return
+	}
+
+	row := fmt.Sprintf("%d. %s - %s", index+1, i.Name, i.Artist)
+
+	fn := lipgloss.NewStyle().PaddingLeft(4).Render
+	if index == m.Index() {
+		fn = func(s ...string) string {
+			return lipgloss.NewStyle().PaddingLeft(2).Bold(true).Foreground(lipgloss.Color("205")).Render("> " + strings.Join(s, " "))
+		}
+	}
+
+	fmt.Fprint(w, fn(row))
+}
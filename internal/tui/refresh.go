@@ -0,0 +1,59 @@
+package tui
+
+import (
+	"limiu82214/lazyAppleMusic/internal/artwork"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"limiu82214/lazyAppleMusic/internal/util"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// refreshPlanner decide what to fetch after the now-playing snapshot arrived.
+// The snapshot is cheap and fetched on every tick, the artwork and the playlist are
+// only fetched again when their identity in the snapshot changed, or when their last fetch failed.
+type refreshPlanner struct {
+	artworkKey  string
+	playlistKey string
+}
+
+func newRefreshPlanner() *refreshPlanner {
+	return &refreshPlanner{}
+}
+
+func (p *refreshPlanner) plan(m topTui, nowPlaying model.NowPlaying) []tea.Cmd {
+	cmds := []tea.Cmd{}
+	if nowPlaying.State == model.PlayerNotRunning && nowPlaying.Playlist.Id == "" {
+		// load everything again once the app is back
+		p.reset()
+		return cmds
+	}
+	// an exported library is still there to browse while the app is not running
+
+	// tracks of the same album share the artwork
+	artworkKey := artwork.Key(nowPlaying.Track)
+	if artworkKey != p.artworkKey {
+		p.artworkKey = artworkKey
+		track := nowPlaying.Track
+		cmds = append(cmds, func() tea.Msg { return m.fetchArtwork(track) })
+	}
+
+	playlistKey := nowPlaying.Playlist.Id + "\x00" + nowPlaying.Playlist.Stamp
+	if playlistKey != p.playlistKey {
+		p.playlistKey = playlistKey
+		cmds = append(cmds, util.ToTeaCmd(m.fetchCurrentPlaylist))
+	}
+	return cmds
+}
+
+func (p *refreshPlanner) reset() {
+	p.resetArtwork()
+	p.resetPlaylist()
+}
+
+func (p *refreshPlanner) resetArtwork() {
+	p.artworkKey = ""
+}
+
+func (p *refreshPlanner) resetPlaylist() {
+	p.playlistKey = ""
+}
@@ -0,0 +1,255 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"limiu82214/lazyAppleMusic/internal/bridge"
+	"limiu82214/lazyAppleMusic/internal/constant"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"limiu82214/lazyAppleMusic/internal/util"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/davecgh/go-spew/spew"
+)
+
+var searchDebug = false
+
+const searchTabName = "Search"
+
+type SearchTui interface {
+	tea.Model
+	SetWidth(width int) SearchTui
+	SetHeight(height int) SearchTui
+	IsCapturingInput() bool
+}
+
+// searchTui search the whole library, unlike the filter of the current playlist
+// which only see the loaded tracks.
+type searchTui struct {
+	ctx        context.Context // cancelled when the TUI quit
+	dump       io.Writer
+	appleMusic bridge.PlayerBridge
+
+	style  lipgloss.Style
+	input  textinput.Model
+	list   list.Model
+	height int
+
+	field     model.SearchField
+	query     string // the query of the results, or of the running search
+	searching bool
+	err       error
+}
+
+func newSearchTui(ctx context.Context, dump io.Writer, bridge bridge.PlayerBridge) SearchTui {
+	input := textinput.New()
+	input.Placeholder = "press / to search the library"
+	input.Prompt = ""
+
+	list := list.New([]list.Item{}, searchDelegate{}, 0, 0)
+	list.SetShowTitle(false)
+	list.SetShowHelp(false)
+	list.SetShowStatusBar(false)
+	list.SetShowPagination(true)
+	list.SetFilteringEnabled(false)
+
+	obj := &searchTui{
+		ctx:        ctx,
+		dump:       dump,
+		appleMusic: bridge,
+
+		input: input,
+		list:  list,
+		field: model.SearchAll,
+	}
+
+	if !searchDebug {
+		obj.dump = io.Discard
+	}
+	return obj
+}
+
+// ======= MAIN
+
+func (m *searchTui) Init() tea.Cmd {
+	return nil
+}
+
+func (m *searchTui) View() string {
+	header := fmt.Sprintf("Search %s: %s", m.field, m.input.View())
+
+	status := ""
+	switch {
+	case m.searching:
+		status = "Searching..."
+	case m.err != nil:
+		status = "Error searching library: " + m.err.Error()
+	case m.query != "":
+		status = fmt.Sprintf("%d results for %q", len(m.list.Items()), m.query)
+	}
+	if m.input.Focused() {
+		status = "<enter>: search, <tab>: search in " + string(m.field.Next()) + ", <esc>: cancel"
+	}
+	status = lipgloss.NewStyle().Faint(true).Render(status)
+
+	return m.style.Render(lipgloss.JoinVertical(lipgloss.Left, header, status, m.list.View()))
+}
+
+func (m *searchTui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	spew.Fprintln(m.dump, "search: ", msg)
+
+	switch msg := msg.(type) {
+	case constant.EventUpdateSearchResults:
+		if msg.Query != m.query || msg.Field != m.field {
+			spew.Fprintln(m.dump, "search: drop stale results", msg.Query, msg.Field)
+			return m, nil
+		}
+		m.searching = false
+		m.err = msg.Err
+		items := make([]list.Item, 0, len(msg.Tracks))
+		for _, track := range msg.Tracks {
+			items = append(items, track)
+		}
+		m.list.ResetSelected()
+		return m, m.list.SetItems(items)
+	case constant.EventFavoriteTrackId:
+		updateListTrack(&m.list, string(msg), toggleFavorited)
+	case constant.EventTrackRatingChanged:
+		updateListTrack(&m.list, msg.TrackId, func(t *model.Track) { t.Rating = msg.Rating })
+	case constant.EventTrackDislikedChanged:
+		updateListTrack(&m.list, msg.TrackId, func(t *model.Track) { setDisliked(t, msg.Disliked) })
+	case tea.KeyMsg:
+		if m.input.Focused() {
+			switch msg.String() {
+			case "enter":
+				m.input.Blur()
+				return m, m.search()
+			case "esc":
+				m.input.Blur()
+				return m, nil
+			case "tab":
+				m.field = m.field.Next()
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.input, cmd = m.input.Update(msg)
+			return m, cmd
+		}
+
+		switch msg.String() {
+		case "/":
+			return m, m.input.Focus()
+		case "k":
+			m.list.CursorUp()
+		case "j":
+			m.list.CursorDown()
+		case "h":
+			m.list.PrevPage()
+		case "l":
+			m.list.NextPage()
+		}
+
+		track, ok := m.list.SelectedItem().(model.Track)
+		if !ok {
+			return m, nil
+		}
+		switch msg.String() {
+		case "f":
+			return m, util.ToTeaCmdMsg(constant.ShouldFavoriteTrackId(track.Id))
+		case "a":
+			return m, util.ToTeaCmdMsg(constant.ShouldAddTracksToPlaylist{track})
+		case "0", "1", "2", "3", "4", "5":
+			return m, util.ToTeaCmdMsg(constant.ShouldRateTrack{TrackId: track.Id, Rating: starsToRating(msg.String())})
+		case "D":
+			return m, util.ToTeaCmdMsg(constant.ShouldSetTrackDisliked{TrackId: track.Id, Disliked: !track.Disliked})
+		case "e", "E":
+			return m, util.ToTeaCmdMsg(constant.ShouldQueueTrack{Track: track, Next: msg.String() == "e"})
+		case "g":
+			// the result may be in no playlist, play it from the library
+			return m, util.ToTeaCmdMsg(constant.ShouldPlayTrackId(track.Id))
+		case "enter":
+			return m, util.ToTeaCmdMsg(constant.ShouldSelectTrackId(track.Id))
+		}
+	default:
+		if m.input.Focused() {
+			var cmd tea.Cmd
+			m.input, cmd = m.input.Update(msg)
+			return m, cmd
+		}
+	}
+
+	return m, nil
+}
+
+// ======= Other
+
+func (m *searchTui) search() tea.Cmd {
+	query := strings.TrimSpace(m.input.Value())
+	if query == "" {
+		return nil
+	}
+	m.query = query
+	m.searching = true
+	m.err = nil
+	field := m.field
+	return func() tea.Msg {
+		tracks, err := m.appleMusic.SearchLibrary(m.ctx, query, field)
+		if err != nil {
+			spew.Fprintln(m.dump, "Error searching library:", err)
+		}
+		return constant.EventUpdateSearchResults{Query: query, Field: field, Tracks: tracks, Err: err}
+	}
+}
+
+func (m *searchTui) SetWidth(width int) SearchTui {
+	m.input.Width = width - lipgloss.Width(fmt.Sprintf("Search %s: ", m.field)) - 1
+	m.list.SetWidth(width)
+	m.style = m.style.Width(width)
+	return m
+}
+func (m *searchTui) SetHeight(height int) SearchTui {
+	m.height = height
+	// the input and the status take a line each
+	m.list.SetHeight(max(height-2, 0))
+	m.style = m.style.Height(height)
+	return m
+}
+
+func (m searchTui) IsCapturingInput() bool {
+	return m.input.Focused()
+}
+
+type searchDelegate struct{}
+
+func (d searchDelegate) Height() int                               { return 1 }
+func (d searchDelegate) Spacing() int                              { return 0 }
+func (d searchDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd { return nil }
+func (d searchDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	i, ok := listItem.(model.Track)
+	if !ok {
+		return
+	}
+
+	row := constant.Unfavorite + " " + i.Name + " - " + i.Artist
+	if i.Favorited {
+		row = constant.Favorite + " " + i.Name + " - " + i.Artist
+	}
+	if i.Album != "" {
+		row += " · " + i.Album
+	}
+	row += trackMarksUi(i)
+
+	fn := lipgloss.NewStyle().PaddingLeft(4).Render
+	if index == m.Index() {
+		fn = func(s ...string) string {
+			return lipgloss.NewStyle().PaddingLeft(2).Bold(true).Foreground(lipgloss.Color("205")).Render("> " + strings.Join(s, " "))
+		}
+	}
+
+	fmt.Fprint(w, fn(row))
+}
@@ -21,7 +21,15 @@ type TabTui interface {
 	SetHeight(height int) TabTui
 	SetWidth(width int) TabTui
 	GetContent(tabName string) tea.Model
+	GetActiveContent() tea.Model
+	SetActiveTab(tabName string) TabTui
 }
+
+// InputCapturer is a tab content taking every key while the user type into it.
+type InputCapturer interface {
+	IsCapturingInput() bool
+}
+
 type tabTui struct {
 	dump io.Writer
 	model.TabTuiData
@@ -93,6 +101,21 @@ func (m *tabTui) View() string {
 			SetWidth(window.GetWidth() - m.styles.windowStyle.GetHorizontalFrameSize())
 		m.TabContent[m.ActiveTab] = ml
 	}
+	if ms, ok := m.TabContent[m.ActiveTab].(SearchTui); ok {
+		ms.SetHeight(window.GetHeight() - m.styles.windowStyle.GetVerticalBorderSize()).
+			SetWidth(window.GetWidth() - m.styles.windowStyle.GetHorizontalFrameSize())
+		m.TabContent[m.ActiveTab] = ms
+	}
+	if mq, ok := m.TabContent[m.ActiveTab].(QueueTui); ok {
+		mq.SetHeight(window.GetHeight() - m.styles.windowStyle.GetVerticalBorderSize()).
+			SetWidth(window.GetWidth() - m.styles.windowStyle.GetHorizontalFrameSize())
+		m.TabContent[m.ActiveTab] = mq
+	}
+	if ml, ok := m.TabContent[m.ActiveTab].(LyricsTui); ok {
+		ml.SetHeight(window.GetHeight() - m.styles.windowStyle.GetVerticalBorderSize()).
+			SetWidth(window.GetWidth() - m.styles.windowStyle.GetHorizontalFrameSize())
+		m.TabContent[m.ActiveTab] = ml
+	}
 
 	doc.WriteString(window.Render(m.TabContent[m.ActiveTab].View()))
 
@@ -110,6 +133,11 @@ func (m *tabTui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.styles.width = msg.Width
 		m.styles.height = msg.Height
 		return m, tea.Batch(cmds...)
+	case tea.KeyMsg:
+		// keys are for the tab the user is looking at
+		c, cmd := m.TabContent[m.ActiveTab].Update(msg)
+		m.TabContent[m.ActiveTab] = c
+		return m, cmd
 	default:
 		for i := range m.TabContent {
 			c, cmd := m.TabContent[i].Update(msg)
@@ -162,6 +190,22 @@ func (m *tabTui) GetContent(tabName string) tea.Model {
 	return nil
 }
 
+func (m *tabTui) GetActiveContent() tea.Model {
+	if m.ActiveTab < 0 || m.ActiveTab >= len(m.TabContent) {
+		return nil
+	}
+	return m.TabContent[m.ActiveTab]
+}
+
+func (m *tabTui) SetActiveTab(tabName string) TabTui {
+	for i, tab := range m.Tabs {
+		if tab == tabName {
+			m.ActiveTab = i
+		}
+	}
+	return m
+}
+
 func (m *tabTui) renderTabs() string {
 
 	if len(m.Tabs) == 0 {
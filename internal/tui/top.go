@@ -1,8 +1,15 @@
 package tui
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"limiu82214/lazyAppleMusic/internal/artwork"
 	"limiu82214/lazyAppleMusic/internal/bridge"
+	"limiu82214/lazyAppleMusic/internal/lyrics"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"limiu82214/lazyAppleMusic/internal/queue"
 	"limiu82214/lazyAppleMusic/internal/util"
 	"time"
 
@@ -15,34 +22,83 @@ import (
 )
 
 const currentPlaylistTabName = "Current Play List"
+const currentPlaylistPageSize = 300
+
 var globalDump io.Writer
 
+// Options tune the behavior of the TUI, zero values fall back to the defaults.
+type Options struct {
+	SeekStep     int      // seconds, for [ and ]
+	LongSeekStep int      // seconds, for { and }
+	LyricsDirs   []string // searched for the LRC files of the tracks
+	Artwork      artwork.Kind
+}
+
+func (o Options) withDefaults() Options {
+	if o.SeekStep <= 0 {
+		o.SeekStep = 5
+	}
+	if o.LongSeekStep <= 0 {
+		o.LongSeekStep = 30
+	}
+	if o.Artwork == "" {
+		o.Artwork = artwork.KindAuto
+	}
+	return o
+}
+
 type topTui struct {
+	ctx        context.Context // every bridge call run within ctx, cancelled on quit
+	cancel     context.CancelFunc
 	dump       io.Writer
 	appleMusic bridge.PlayerBridge
+	options    Options
 	width      int
 	height     int
 
 	playingTui PlayingTui
 	tabTui     TabTui
 	helpTui    HelpTui
+	picker     PlaylistPickerTui
+	confirm    ConfirmTui
+	notice     string // last error, shown above the help until the next key
+
+	queue    *queue.Queue
+	watcher  *queue.Watcher
+	refresh  *refreshPlanner
+	artworks *artwork.Renderer
 }
 
-func InitialTopTui(dump io.Writer) topTui {
+func InitialTopTui(ctx context.Context, dump io.Writer, appleMusic bridge.PlayerBridge, options Options) topTui {
 	globalDump = dump
-	appleMusic := bridge.NewAppleMusicBridge(dump)
+	options = options.withDefaults()
+	upNext := queue.New()
+	ctx, cancel := context.WithCancel(ctx)
 	return topTui{
+		ctx:        ctx,
+		cancel:     cancel,
 		dump:       dump,
 		appleMusic: appleMusic,
+		options:    options,
 
 		playingTui: newPlayingTui(dump, appleMusic),
 		tabTui: newTabTui(dump, []string{currentPlaylistTabName,
-			"Not Implemented Yet",
+			searchTabName,
+			queueTabName,
+			lyricsTabName,
 		}, []tea.Model{
 			newCurrentPlaylistTui(dump, appleMusic),
-			emptyModel{},
+			newSearchTui(ctx, dump, appleMusic),
+			newQueueTui(dump, upNext),
+			newLyricsTui(ctx, dump, appleMusic, lyricsResolver(options.LyricsDirs)),
 		}, 0),
-		helpTui: newHelpTui(dump),
+		helpTui:  newHelpTui(dump),
+		picker:   newPlaylistPickerTui(ctx, dump, appleMusic),
+		confirm:  newConfirmTui(dump),
+		queue:    upNext,
+		watcher:  queue.NewWatcher(upNext),
+		refresh:  newRefreshPlanner(),
+		artworks: artwork.NewRenderer(artwork.DefaultRenderCacheSize, artwork.NewEncoder(options.Artwork)),
 	}
 }
 
@@ -54,10 +110,19 @@ func doTick() tea.Cmd {
 
 // ======= MAIN
 
+// watchPlayer wait for the next change pushed by the player, when the bridge can watch it
+func (m topTui) watchPlayer() tea.Cmd {
+	if w, ok := m.appleMusic.(bridge.Watcher); ok {
+		return w.WatchChanges(m.ctx)
+	}
+	return nil
+}
+
 func (m topTui) Init() tea.Cmd {
-	m.fetchData()
 	return tea.Batch(
+		tea.Batch(m.fetchData()...),
 		doTick(),
+		m.watchPlayer(),
 	)
 }
 
@@ -72,10 +137,21 @@ func (m topTui) View() string {
 
 	// footer
 	footer := m.helpTui.Width(width).View()
+	if m.confirm.IsOpen() {
+		footer = m.confirm.Width(width).View()
+	}
+	if m.notice != "" {
+		footer = lipgloss.JoinVertical(lipgloss.Left, noticeStyle.Width(width).Render(m.notice), footer)
+	}
 	leftHeight -= lipgloss.Height(footer)
 
 	// content
-	content := m.tabTui.SetWidth(width).SetHeight(leftHeight).View()
+	content := ""
+	if m.picker.IsOpen() {
+		content = m.picker.SetWidth(width).SetHeight(leftHeight).View()
+	} else {
+		content = m.tabTui.SetWidth(width).SetHeight(leftHeight).View()
+	}
 
 	// leftHeight -= lipgloss.Height(content) + lipgloss.ASCIIBorder().GetTopSize() + lipgloss.ASCIIBorder().GetBottomSize()
 	// spew.Fprintln(m.dump, "height:", m.height, "header:", lipgloss.Height(header), "content:", lipgloss.Height(content), "footer:", lipgloss.Height(footer))
@@ -94,42 +170,109 @@ func (m topTui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	cmds := []tea.Cmd{}
 
 	switch msg := msg.(type) {
-	case constant.EventUpdateTrackData:
-		spew.Fprintln(m.dump, "Top EventUpdateTrackData:", util.JsonMarshalWhatever(msg))
+	case constant.EventUpdateNowPlaying:
+		spew.Fprintln(m.dump, "Top EventUpdateNowPlaying:", util.JsonMarshalWhatever(msg))
 		pm, cmd := m.playingTui.Update(msg)
+		cmds = append(cmds, cmd)
 		m.playingTui, _ = pm.(PlayingTui)
 
-		return m, cmd
+		// the lyrics follow the current track
+		tt, cmd := m.tabTui.Update(msg)
+		cmds = append(cmds, cmd)
+		m.tabTui, _ = tt.(TabTui)
+		cmds = append(cmds, m.syncPosition())
+
+		cmds = append(cmds, m.refresh.plan(m, model.NowPlaying(msg))...)
+		if next, ok := m.watcher.Observe(model.NowPlaying(msg)); ok {
+			spew.Fprintln(m.dump, "Top play queued track:", next.Id)
+			cmds = append(cmds, playFailed(m.appleMusic.PlayTrackById(m.ctx, next.Id)), util.ToTeaCmdMsg(constant.EventQueueChanged{}))
+		}
+		return m, tea.Batch(cmds...)
+	case constant.EventUpdateArtwork:
+		spew.Fprintln(m.dump, "Top EventUpdateArtwork:", util.JsonMarshalWhatever(msg))
+		pm, cmd := m.playingTui.Update(msg)
+		cmds = append(cmds, cmd)
+		m.playingTui, _ = pm.(PlayingTui)
+
+		cmds = append(cmds, m.renderArtwork(string(msg)))
+		return m, tea.Batch(cmds...)
+	case constant.EventArtworkFailed:
+		spew.Fprintln(m.dump, "Top EventArtworkFailed:", msg.Err)
+		// keep the artwork shown and try again on the next tick
+		m.refresh.resetArtwork()
+		return m, nil
 	case constant.EventUpdateCurrentAlbumImg:
 		// spew.Fprintln(m.dump, "Top EventUpdateCurrentAlbumImg:", util.JsonMarshalWhatever(msg))
 		pm, cmd := m.playingTui.Update(msg)
 		m.playingTui, _ = pm.(PlayingTui)
 
 		return m, cmd
-	case constant.EventUpdatePlayerPosition:
-		spew.Fprintln(m.dump, "Top EventUpdatePlayerPosition:", util.JsonMarshalWhatever(msg))
+	case constant.EventPlayerPositionChanged:
+		spew.Fprintln(m.dump, "Top EventPlayerPositionChanged:", util.JsonMarshalWhatever(msg))
+		pm, cmd := m.playingTui.Update(msg)
+		m.playingTui, _ = pm.(PlayingTui)
+
+		return m, tea.Batch(cmd, m.syncPosition())
+	case constant.EventPlayerStateChanged:
+		spew.Fprintln(m.dump, "Top EventPlayerStateChanged:", util.JsonMarshalWhatever(msg))
+		return m, util.ToTeaCmd(m.fetchPlayerState)
+	case constant.EventUpdatePlayerState:
+		spew.Fprintln(m.dump, "Top EventUpdatePlayerState:", util.JsonMarshalWhatever(msg))
+		wasRunning := m.playingTui.GetPlayerState() != model.PlayerNotRunning
+		pm, cmd := m.playingTui.Update(msg)
+		cmds = append(cmds, cmd)
+		m.playingTui, _ = pm.(PlayingTui)
+
+		// just launched, nothing was loaded yet
+		if !wasRunning && model.PlayerState(msg) != model.PlayerNotRunning {
+			cmds = append(cmds, m.fetchData()...)
+		}
+		cmds = append(cmds, m.syncPosition())
+		return m, tea.Batch(cmds...)
+	case constant.EventUpdateShuffle, constant.EventUpdateRepeat:
+		spew.Fprintln(m.dump, "Top EventUpdatePlayMode:", util.JsonMarshalWhatever(msg))
 		pm, cmd := m.playingTui.Update(msg)
 		m.playingTui, _ = pm.(PlayingTui)
 
 		return m, cmd
 	case constant.EventUpdateCurrentPlaylist:
+		if msg.Err != nil {
+			spew.Fprintln(m.dump, "Top EventUpdateCurrentPlaylist:", msg.Offset, msg.Err)
+			// keep the tracks shown and load the playlist again on the next tick
+			m.refresh.resetPlaylist()
+			return m, nil
+		}
 		cp := m.tabTui.GetContent(currentPlaylistTabName)
 		if cp != nil {
 			if currentPlaylist, ok := cp.(CurrentPlaylistTui); ok {
 				if currentPlaylist.IsUnFiltered() {
-					spew.Fprintln(m.dump, "Top EventUpdateCurrentPlaylist:", util.JsonMarshalWhatever(msg))
+					spew.Fprintln(m.dump, "Top EventUpdateCurrentPlaylist:", msg.Playlist.Id, msg.Offset, len(msg.Playlist.Tracks), msg.Playlist.TrackCount)
 					tt, cmd := m.tabTui.Update(msg)
+					cmds = append(cmds, cmd)
 					m.tabTui, _ = tt.(TabTui)
-					return m, cmd
+
+					// keep loading while the page was accepted and more remain
+					next, ok := currentPlaylist.NextPageOffset()
+					if ok && len(msg.Playlist.Tracks) > 0 && next == msg.Offset+len(msg.Playlist.Tracks) {
+						cmds = append(cmds, m.fetchCurrentPlaylistPage(msg.Playlist, next))
+					}
+					return m, tea.Batch(cmds...)
 				}
+				// dropped while filtering, load it again on the next tick
+				m.refresh.resetPlaylist()
 			}
 		}
 	case constant.ShouldFavoriteTrackId:
 		spew.Fprintln(m.dump, "Top ShouldFavoriteTrack:", util.JsonMarshalWhatever(msg))
-		return m, m.appleMusic.FavoriteTrackByTrackId(string(msg))
+		return m, m.appleMusic.FavoriteTrackByTrackId(m.ctx, string(msg))
 	case constant.ShouldPlayTrackId:
 		spew.Fprintln(m.dump, "Top ShouldPlayTrackId:", util.JsonMarshalWhatever(msg))
-		return m, m.appleMusic.PlayTrackById(string(msg))
+		m.watcher.Expect()
+		return m, playFailed(m.appleMusic.PlayTrackById(m.ctx, string(msg)))
+	case constant.ShouldPlayTrackInPlaylist:
+		spew.Fprintln(m.dump, "Top ShouldPlayTrackInPlaylist:", util.JsonMarshalWhatever(msg))
+		m.watcher.Expect()
+		return m, playFailed(m.appleMusic.PlayTrackInPlaylist(m.ctx, msg.PlaylistId, msg.TrackId))
 	case constant.ShouldClearFilter:
 		spew.Fprintln(m.dump, "Top ShouldClearFilter:", util.JsonMarshalWhatever(msg))
 		tt, cmd := m.tabTui.Update(msg)
@@ -138,13 +281,61 @@ func (m topTui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Batch(cmds...)
 	case constant.ShouldSelectTrackId:
 		spew.Fprintln(m.dump, "Top ShouldSelectTrackId:", util.JsonMarshalWhatever(msg))
+		m.tabTui.SetActiveTab(currentPlaylistTabName)
 		tt, cmd := m.tabTui.Update(msg)
 		cmds = append(cmds, cmd)
 		m.tabTui, _ = tt.(TabTui)
 		return m, tea.Batch(cmds...)
 
-	case constant.EventFavoriteTrackId:
-		spew.Fprintln(m.dump, "Top EventFavoriteTrackId:", util.JsonMarshalWhatever(msg))
+	case constant.ShouldQueueTrack:
+		spew.Fprintln(m.dump, "Top ShouldQueueTrack:", util.JsonMarshalWhatever(msg))
+		if msg.Next {
+			m.queue.PlayNext(msg.Track)
+		} else {
+			m.queue.PlayLater(msg.Track)
+		}
+		return m, util.ToTeaCmdMsg(constant.EventQueueChanged{})
+	case constant.EventQueueChanged:
+		tt, cmd := m.tabTui.Update(msg)
+		m.tabTui, _ = tt.(TabTui)
+		return m, cmd
+	case constant.ShouldAddTracksToPlaylist:
+		spew.Fprintln(m.dump, "Top ShouldAddTracksToPlaylist:", util.JsonMarshalWhatever(msg))
+		return m, m.picker.Open(msg)
+	case constant.ShouldRemoveTrackFromPlaylist:
+		spew.Fprintln(m.dump, "Top ShouldRemoveTrackFromPlaylist:", util.JsonMarshalWhatever(msg))
+		m.confirm.Ask(
+			fmt.Sprintf("Remove %q from %q?", msg.Track.Name, msg.Playlist.Name),
+			m.appleMusic.RemoveTracksFromPlaylist(m.ctx, msg.Playlist.Id, []string{msg.Track.Id}),
+		)
+		return m, nil
+	case constant.EventUpdateUserPlaylists:
+		pm, cmd := m.picker.Update(msg)
+		m.picker, _ = pm.(PlaylistPickerTui)
+		return m, cmd
+	case constant.EventPlaylistChanged, constant.EventPlaylistsChanged:
+		spew.Fprintln(m.dump, "Top EventPlaylistChanged:", util.JsonMarshalWhatever(msg))
+		// the current playlist may be the one changed
+		m.refresh.resetPlaylist()
+		return m, tea.Batch(m.fetchData()...)
+	case constant.EventUpdateSearchResults:
+		spew.Fprintln(m.dump, "Top EventUpdateSearchResults:", msg.Query, msg.Field, len(msg.Tracks), msg.Err)
+		tt, cmd := m.tabTui.Update(msg)
+		m.tabTui, _ = tt.(TabTui)
+		return m, cmd
+	case constant.EventUpdateLyrics:
+		spew.Fprintln(m.dump, "Top EventUpdateLyrics:", msg.TrackId, len(msg.Lyrics), msg.Err)
+		tt, cmd := m.tabTui.Update(msg)
+		m.tabTui, _ = tt.(TabTui)
+		return m, cmd
+	case constant.ShouldRateTrack:
+		spew.Fprintln(m.dump, "Top ShouldRateTrack:", util.JsonMarshalWhatever(msg))
+		return m, m.appleMusic.SetRating(m.ctx, msg.TrackId, msg.Rating)
+	case constant.ShouldSetTrackDisliked:
+		spew.Fprintln(m.dump, "Top ShouldSetTrackDisliked:", util.JsonMarshalWhatever(msg))
+		return m, m.appleMusic.SetDisliked(m.ctx, msg.TrackId, msg.Disliked)
+	case constant.EventFavoriteTrackId, constant.EventTrackRatingChanged, constant.EventTrackDislikedChanged:
+		spew.Fprintln(m.dump, "Top track marks changed:", util.JsonMarshalWhatever(msg))
 		pm, cmd := m.playingTui.Update(msg)
 		cmds = append(cmds, cmd)
 		m.playingTui, _ = pm.(PlayingTui)
@@ -160,13 +351,19 @@ func (m topTui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		pm, cmd := m.playingTui.Update(msg)
 		m.playingTui, _ = pm.(PlayingTui)
 
-		return m, cmd
+		// the track is over, look at what is playing now without waiting for the next tick
+		fetch := tea.Tick(time.Second, func(time.Time) tea.Msg { return m.fetchNowPlaying() })
+		return m, tea.Batch(cmd, fetch)
 
 	case timer.TickMsg:
 		spew.Fprintln(m.dump, "Top TickMsg:", util.JsonMarshalWhatever(msg))
 		pm, cmd := m.playingTui.Update(msg)
 		m.playingTui, _ = pm.(PlayingTui)
 
+		return m, tea.Batch(cmd, m.syncPosition())
+	case constant.LyricsTickMsg:
+		tt, cmd := m.tabTui.Update(msg)
+		m.tabTui, _ = tt.(TabTui)
 		return m, cmd
 
 	case constant.TickMsg:
@@ -176,46 +373,103 @@ func (m topTui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, doTick())
 		return m, tea.Batch(cmds...)
 
+	case constant.EventPlayerChanged:
+		spew.Fprintln(m.dump, "Top EventPlayerChanged:", util.JsonMarshalWhatever(msg))
+		// the stamp of the current playlist tell whether its tracks are to be loaded again
+		cmds := m.fetchData()
+		cmds = append(cmds, m.watchPlayer())
+		return m, tea.Batch(cmds...)
+
 	case tea.WindowSizeMsg:
 		spew.Fprintln(m.dump, "Top WindowSizeMsg:", util.JsonMarshalWhatever(msg))
 		m.width = msg.Width
 		m.height = msg.Height
+		// the artwork is rendered for the window size, the file is already there
+		return m, m.renderArtwork(m.playingTui.GetArtworkPath())
 
 	case constant.EventTrackChanged:
 		spew.Fprintln(m.dump, "Top EventTrackChanged:", util.JsonMarshalWhatever(msg))
 		cmds := m.fetchData()
 		return m, tea.Batch(cmds...)
 
+	case constant.EventPlaylistPickerFailed:
+		spew.Fprintln(m.dump, "Top EventPlaylistPickerFailed:", msg.Err)
+		m.picker.Close()
+		return m.showError(msg.Err)
+	case constant.EventPlayFailed:
+		spew.Fprintln(m.dump, "Top EventPlayFailed:", msg.Err)
+		// the track did not change, the next change is not the one expected
+		m.watcher.Cancel()
+		return m.showError(msg.Err)
+	case error:
+		spew.Fprintln(m.dump, "Top error:", msg)
+		return m.showError(msg)
+
 	default:
-		cp := m.tabTui.GetContent(currentPlaylistTabName)
-		if cp != nil {
-			if currentPlaylist, ok := cp.(CurrentPlaylistTui); ok {
-				if currentPlaylist.IsFiltering() {
-					spew.Fprintln(m.dump, "Top KeyMsg: currentPlaylist is filtering, passing to currentPlaylist")
-					_, cmd := currentPlaylist.Update(msg)
-					return m, cmd
-				}
-			}
+		if _, ok := msg.(tea.KeyMsg); ok {
+			m.notice = ""
+		}
+		if _, ok := msg.(tea.KeyMsg); ok && m.confirm.IsOpen() {
+			_, cmd := m.confirm.Update(msg)
+			return m, cmd
+		}
+		if m.picker.IsOpen() {
+			pm, cmd := m.picker.Update(msg)
+			m.picker, _ = pm.(PlaylistPickerTui)
+			return m, cmd
+		}
+		if c, ok := m.tabTui.GetActiveContent().(InputCapturer); ok && c.IsCapturingInput() {
+			spew.Fprintln(m.dump, "Top KeyMsg: active tab is capturing input, passing to tabs")
+			tt, cmd := m.tabTui.Update(msg)
+			m.tabTui, _ = tt.(TabTui)
+			return m, cmd
 		}
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
 			spew.Fprintln(m.dump, "Top KeyMsg:", util.JsonMarshalWhatever(msg))
 			switch msg.String() {
 			case "ctrl+c", "q":
+				// stop the scripts still running, their commands would never be read
+				m.cancel()
 				return m, tea.Quit
 			case "p":
-				return m, m.appleMusic.PlayPause()
+				return m, m.appleMusic.PlayPause(m.ctx)
+			case "o":
+				return m, m.appleMusic.LaunchApp(m.ctx)
 			case "n":
-				return m, m.appleMusic.NextTrack()
+				if next, ok := m.queue.Pop(); ok {
+					m.watcher.Expect()
+					return m, tea.Batch(playFailed(m.appleMusic.PlayTrackById(m.ctx, next.Id)), util.ToTeaCmdMsg(constant.EventQueueChanged{}))
+				}
+				return m, m.appleMusic.NextTrack(m.ctx)
 			case "b":
-				return m, m.appleMusic.PreviousTrack()
+				m.watcher.Expect()
+				return m, playFailed(m.appleMusic.PreviousTrack(m.ctx))
 			case "u":
-				return m, m.appleMusic.IncreaseVolume()
+				return m, m.appleMusic.IncreaseVolume(m.ctx)
 			case "d":
-				return m, m.appleMusic.DecreaseVolume()
+				return m, m.appleMusic.DecreaseVolume(m.ctx)
 			case "F":
-				return m, m.appleMusic.FavoriteCurrentTrack()
+				return m, m.appleMusic.FavoriteCurrentTrack(m.ctx)
+			case "alt+0", "alt+1", "alt+2", "alt+3", "alt+4", "alt+5":
+				return m, m.appleMusic.SetRating(m.ctx, m.playingTui.GetCurrentTrack().Id, starsToRating(msg.String()))
+			case "alt+D":
+				track := m.playingTui.GetCurrentTrack()
+				return m, m.appleMusic.SetDisliked(m.ctx, track.Id, !track.Disliked)
+			case "z":
+				return m, m.appleMusic.SetShuffle(m.ctx, !m.playingTui.GetShuffle())
+			case "R":
+				return m, m.appleMusic.SetRepeat(m.ctx, m.playingTui.GetRepeat().Next())
+			case "[":
+				return m, m.appleMusic.Seek(m.ctx, -m.options.SeekStep)
+			case "]":
+				return m, m.appleMusic.Seek(m.ctx, m.options.SeekStep)
+			case "{":
+				return m, m.appleMusic.Seek(m.ctx, -m.options.LongSeekStep)
+			case "}":
+				return m, m.appleMusic.Seek(m.ctx, m.options.LongSeekStep)
 			case "r":
+				m.refresh.reset()
 				cmds := m.fetchData()
 				return m, tea.Batch(cmds...)
 			case "f":
@@ -246,6 +500,14 @@ func (m topTui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				tt, cmd := m.tabTui.Update(msg)
 				m.tabTui, _ = tt.(TabTui)
 				return m, cmd
+			case "a", "x", "e", "E", "J", "K", "D", "L", "0", "1", "2", "3", "4", "5":
+				tt, cmd := m.tabTui.Update(msg)
+				m.tabTui, _ = tt.(TabTui)
+				return m, cmd
+			case "enter":
+				tt, cmd := m.tabTui.Update(msg)
+				m.tabTui, _ = tt.(TabTui)
+				return m, cmd
 			case tea.KeyEscape.String():
 				return m, util.ToTeaCmdMsg(constant.ShouldClearFilter{})
 			case ">":
@@ -263,47 +525,145 @@ func (m topTui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// syncPosition give the position of the playing view to the tabs, the lyrics highlight the line sung
+func (m topTui) syncPosition() tea.Cmd {
+	tt, cmd := m.tabTui.Update(constant.EventUpdatePlayerPosition{
+		Position: m.playingTui.GetPosition(),
+		Playing:  m.playingTui.GetPlayerState() == model.PlayerPlaying,
+	})
+	m.tabTui, _ = tt.(TabTui)
+	return cmd
+}
+
+// lyricsResolver return the resolver of the LRC files, nil without directories
+func lyricsResolver(dirs []string) *lyrics.Resolver {
+	if len(dirs) == 0 {
+		return nil
+	}
+	return lyrics.NewResolver(dirs...)
+}
+
 // ====== fetch
 
 func (m *topTui) fetchData() []tea.Cmd {
 	cmds := []tea.Cmd{}
-	cmds = append(cmds, util.ToTeaCmd(m.fetchCurrentTrack))
-	cmds = append(cmds, util.ToTeaCmd(m.fetchCurrentAlbumImg))
-	cmds = append(cmds, util.ToTeaCmd(m.fetchPlayerPosition))
-	cmds = append(cmds, util.ToTeaCmd(m.fetchCurrentPlaylist)) // TODO: consider goroutine because it is slow, make sure using mutex prevent concurrent access
+	// the artwork and the playlist are fetched by m.refresh once the snapshot arrive
+	cmds = append(cmds, util.ToTeaCmd(m.fetchNowPlaying))
 	return cmds
 }
 
-func (m topTui) fetchCurrentTrack() constant.EventUpdateTrackData {
-	track, err := m.appleMusic.GetCurrentTrack()
+// fetchNowPlaying return the snapshot of the player, or the error which is shown as a notice.
+// A failed poll keep the last snapshot, the player is only "not running" when the bridge say so.
+func (m topTui) fetchNowPlaying() tea.Msg {
+	nowPlaying, err := m.appleMusic.GetNowPlaying(m.ctx)
 	if err != nil {
-		spew.Fprintln(m.dump, "Error fetching current track:", err)
-		track.Name = err.Error()
+		spew.Fprintln(m.dump, "Error fetching now playing:", err)
+		return err
 	}
-	return constant.EventUpdateTrackData(track)
+	return constant.EventUpdateNowPlaying(nowPlaying)
 }
 
-func (m topTui) fetchCurrentAlbumImg() constant.EventUpdateCurrentAlbumImg {
-	currentAlbumImg, err := m.appleMusic.GetCurrentAlbum(int(float64(m.height)/2.5), int(float64(m.height)/2.5))
+func (m topTui) fetchPlayerState() tea.Msg {
+	state, err := m.appleMusic.GetPlayerState(m.ctx)
 	if err != nil {
-		currentAlbumImg = "Error fetching current album: " + err.Error()
+		spew.Fprintln(m.dump, "Error fetching player state:", err)
+		return err
 	}
-	return constant.EventUpdateCurrentAlbumImg(currentAlbumImg)
+	return constant.EventUpdatePlayerState(state)
 }
 
-func (m topTui) fetchPlayerPosition() constant.EventUpdatePlayerPosition {
-	playerPosition, err := m.appleMusic.GetPlayerPosition()
+func (m topTui) fetchArtwork(track model.Track) tea.Msg {
+	path, err := m.appleMusic.GetArtwork(m.ctx, track)
 	if err != nil {
-		spew.Fprintln(m.dump, "Error fetching player position:", err)
-		playerPosition = 0
+		return constant.EventArtworkFailed{Err: err}
 	}
-	return constant.EventUpdatePlayerPosition(playerPosition)
+	return constant.EventUpdateArtwork(path)
 }
 
+// renderArtwork render the artwork file for the window size, sizes already rendered come from m.artworks
+func (m topTui) renderArtwork(path string) tea.Cmd {
+	if path == "" {
+		return nil
+	}
+	// a cell is about twice as high as wide, twice the columns keep the artwork square
+	rows := int(float64(m.height) / 2.5)
+	return func() tea.Msg {
+		currentAlbumImg, err := m.artworks.Render(path, rows*2, rows)
+		if err != nil {
+			currentAlbumImg = "Error rendering current album: " + err.Error()
+		}
+		return constant.EventUpdateCurrentAlbumImg(currentAlbumImg)
+	}
+}
+
+// fetchCurrentPlaylist load the first page, the next pages are requested when a page arrive
 func (m topTui) fetchCurrentPlaylist() constant.EventUpdateCurrentPlaylist {
-	currentPlaylist, err := m.appleMusic.GetCurrentPlaylist()
+	currentPlaylist, err := m.appleMusic.GetCurrentPlaylistInfo(m.ctx)
 	if err != nil {
-		spew.Fdump(m.dump, "Error fetching current playlist:", err)
+		return constant.EventUpdateCurrentPlaylist{Playlist: currentPlaylist, Err: err}
+	}
+	return m.loadCurrentPlaylistPage(currentPlaylist, 0)
+}
+
+func (m topTui) fetchCurrentPlaylistPage(playlist model.Playlist, offset int) tea.Cmd {
+	return func() tea.Msg {
+		return m.loadCurrentPlaylistPage(playlist, offset)
+	}
+}
+
+func (m topTui) loadCurrentPlaylistPage(playlist model.Playlist, offset int) constant.EventUpdateCurrentPlaylist {
+	tracks, err := m.appleMusic.GetCurrentPlaylistTracks(m.ctx, offset, currentPlaylistPageSize)
+	if err != nil {
+		return constant.EventUpdateCurrentPlaylist{Playlist: playlist, Offset: offset, Err: err}
+	}
+	playlist.Tracks = tracks
+	return constant.EventUpdateCurrentPlaylist{Playlist: playlist, Offset: offset}
+}
+
+// ====== errors
+
+// playFailed report the error answered by cmd, a play the watcher expect, as EventPlayFailed
+func playFailed(cmd tea.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		msg := cmd()
+		if err, ok := msg.(error); ok {
+			return constant.EventPlayFailed{Err: err}
+		}
+		return msg
+	}
+}
+
+var noticeStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("203")).Bold(true)
+
+// showError tell the user what went wrong
+func (m topTui) showError(err error) (tea.Model, tea.Cmd) {
+	if errors.Is(err, bridge.ErrAppNotRunning) {
+		// the player view already tell how to launch Music
+		return m, util.ToTeaCmdMsg(constant.EventUpdatePlayerState(model.PlayerNotRunning))
+	}
+	m.notice = errorNotice(err)
+	return m, nil
+}
+
+// errorNotice tell the user what went wrong, with a hint for the errors they can fix
+func errorNotice(err error) string {
+	var parseErr *bridge.ParseError
+	switch {
+	case errors.Is(err, bridge.ErrPermissionDenied):
+		return "Not allowed to control Music, allow this terminal in System Settings > Privacy & Security > Automation"
+	case errors.Is(err, bridge.ErrTimeout):
+		return "Music did not answer in time, try again"
+	case errors.Is(err, bridge.ErrTrackNotFound):
+		return "Track not found, press r to refresh"
+	case errors.Is(err, bridge.ErrPlaylistNotFound):
+		return "Playlist not found, press r to refresh"
+	case errors.Is(err, bridge.ErrPlaylistChanged):
+		return "The playlist changed, press r to refresh"
+	case errors.Is(err, bridge.ErrNotSupported):
+		return "Not supported by this player"
+	case errors.As(err, &parseErr):
+		return "Unexpected answer from Music: " + parseErr.Err.Error()
+	default:
+		return "Error: " + err.Error()
 	}
-	return constant.EventUpdateCurrentPlaylist(currentPlaylist)
 }
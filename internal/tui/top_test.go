@@ -0,0 +1,170 @@
+package tui
+
+import (
+	"context"
+	"flag"
+	"io"
+	"limiu82214/lazyAppleMusic/internal/artwork"
+	"limiu82214/lazyAppleMusic/internal/bridge"
+	"limiu82214/lazyAppleMusic/internal/constant"
+	"limiu82214/lazyAppleMusic/internal/model"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// go test ./internal/tui -update write the views into testdata
+var update = flag.Bool("update", false, "update the golden files")
+
+const (
+	testWidth  = 80
+	testHeight = 30
+)
+
+func TestMain(m *testing.M) {
+	// no color, the golden files hold plain text
+	lipgloss.SetColorProfile(termenv.Ascii)
+	os.Exit(m.Run())
+}
+
+var testLibrary = bridge.FakeLibrary{
+	Volume: 40,
+	Tracks: []model.Track{
+		{Id: "T1", Name: "First Song", Artist: "Band", Album: "Album", Duration: 185, Favorited: true},
+		{Id: "T2", Name: "Second Song", Artist: "Band", Album: "Album", Duration: 242, Rating: 80},
+		{Id: "T3", Name: "Third Song", Artist: "Other Band", Album: "Other", Duration: 61, Disliked: true},
+	},
+	Playlists: []bridge.FakeLibraryPlaylist{
+		{Id: "P1", Name: "Mix", Tracks: []string{"T1", "T2", "T3"}},
+	},
+}
+
+// newTestBridge return the fake player, paused on the first track so the views do not move with the clock
+func newTestBridge(t *testing.T) bridge.PlayerBridge {
+	t.Helper()
+	b := bridge.NewFakeBridge(io.Discard, testLibrary, nil)
+	if err, ok := b.Pause(context.Background())().(error); ok {
+		t.Fatalf("Pause: %v", err)
+	}
+	return b
+}
+
+func testNowPlaying(t *testing.T, b bridge.PlayerBridge) constant.EventUpdateNowPlaying {
+	t.Helper()
+	nowPlaying, err := b.GetNowPlaying(context.Background())
+	if err != nil {
+		t.Fatalf("GetNowPlaying: %v", err)
+	}
+	return constant.EventUpdateNowPlaying(nowPlaying)
+}
+
+func testPlaylistPage(t *testing.T, b bridge.PlayerBridge) constant.EventUpdateCurrentPlaylist {
+	t.Helper()
+	playlist, err := b.GetCurrentPlaylistInfo(context.Background())
+	if err != nil {
+		t.Fatalf("GetCurrentPlaylistInfo: %v", err)
+	}
+	playlist.Tracks, err = b.GetCurrentPlaylistTracks(context.Background(), 0, currentPlaylistPageSize)
+	if err != nil {
+		t.Fatalf("GetCurrentPlaylistTracks: %v", err)
+	}
+	return constant.EventUpdateCurrentPlaylist{Playlist: playlist}
+}
+
+// assertGolden compare view with testdata/name.golden
+func assertGolden(t *testing.T, name, view string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(view), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file, run with -update to create it: %v", err)
+	}
+	if view != string(want) {
+		t.Errorf("view of %s differ from %s\n got:\n%s\nwant:\n%s", name, path, view, want)
+	}
+}
+
+func TestTopTuiView(t *testing.T) {
+	b := newTestBridge(t)
+	var m tea.Model = InitialTopTui(context.Background(), io.Discard, b, Options{Artwork: artwork.KindASCII})
+	for _, msg := range []tea.Msg{
+		tea.WindowSizeMsg{Width: testWidth, Height: testHeight},
+		testNowPlaying(t, b),
+		testPlaylistPage(t, b),
+	} {
+		m, _ = m.Update(msg)
+	}
+	assertGolden(t, "top", m.View())
+
+	// a failed poll keep the player and show what went wrong
+	m, _ = m.Update(bridge.ErrPermissionDenied)
+	assertGolden(t, "top_error", m.View())
+}
+
+// a failed fetch keep what is shown, and is retried on the next tick
+func TestTopTuiFailedFetch(t *testing.T) {
+	b := newTestBridge(t)
+	nowPlaying := testNowPlaying(t, b)
+	var m tea.Model = InitialTopTui(context.Background(), io.Discard, b, Options{Artwork: artwork.KindASCII})
+	for _, msg := range []tea.Msg{
+		tea.WindowSizeMsg{Width: testWidth, Height: testHeight},
+		nowPlaying,
+		testPlaylistPage(t, b),
+	} {
+		m, _ = m.Update(msg)
+	}
+	loaded := m.View()
+
+	m, _ = m.Update(constant.EventUpdateCurrentPlaylist{Err: bridge.ErrTimeout})
+	m, _ = m.Update(constant.EventArtworkFailed{Err: bridge.ErrTimeout})
+	if view := m.View(); view != loaded {
+		t.Errorf("a failed fetch changed the view\n got:\n%s\nwant:\n%s", view, loaded)
+	}
+	if cmds := m.(topTui).refresh.plan(m.(topTui), model.NowPlaying(nowPlaying)); len(cmds) != 2 {
+		t.Errorf("the next tick fetch %d things, want the artwork and the playlist again", len(cmds))
+	}
+}
+
+// a play that failed does not swallow the next track change, the queue play over it
+func TestTopTuiFailedPlay(t *testing.T) {
+	b := newTestBridge(t)
+	nowPlaying := testNowPlaying(t, b)
+	var m tea.Model = InitialTopTui(context.Background(), io.Discard, b, Options{Artwork: artwork.KindASCII})
+	m, _ = m.Update(nowPlaying)
+	m, _ = m.Update(constant.ShouldQueueTrack{Track: testLibrary.Tracks[2]})
+
+	m, _ = m.Update(constant.ShouldPlayTrackId(testLibrary.Tracks[1].Id))
+	m, _ = m.Update(constant.EventPlayFailed{Err: bridge.ErrTrackNotFound})
+	if notice := m.(topTui).notice; notice == "" {
+		t.Error("the failed play is not shown")
+	}
+
+	nowPlaying.Track = testLibrary.Tracks[1]
+	m, _ = m.Update(nowPlaying)
+	if n := m.(topTui).queue.Len(); n != 0 {
+		t.Errorf("the track change after a failed play left %d queued tracks, want the queued track played", n)
+	}
+}
+
+func TestPlayFailed(t *testing.T) {
+	msg := playFailed(func() tea.Msg { return bridge.ErrTrackNotFound })()
+	if failed, ok := msg.(constant.EventPlayFailed); !ok || failed.Err != bridge.ErrTrackNotFound {
+		t.Errorf("playFailed = %#v, want EventPlayFailed", msg)
+	}
+	msg = playFailed(func() tea.Msg { return nil })()
+	if msg != nil {
+		t.Errorf("playFailed = %#v, want the message of the command", msg)
+	}
+}
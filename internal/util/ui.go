@@ -1,6 +1,18 @@
 package util
 
-import "strings"
+import (
+	"limiu82214/lazyAppleMusic/internal/constant"
+	"strings"
+)
+
+// RatingUi return the stars of a 0-100 rating, empty when the track is not rated
+func RatingUi(rating int) string {
+	stars := min(max(rating, 0), 100) / 20
+	if stars == 0 {
+		return ""
+	}
+	return strings.Repeat(constant.StarFull, stars) + strings.Repeat(constant.StarEmpty, 5-stars)
+}
 
 func ProgressBarUi(percent int, length int) string {
 	const (